@@ -1,9 +1,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,6 +23,212 @@ type Config struct {
 	WorkerCount       int
 	TransportOverride string
 	QueryAllTypes     bool
+	TLSIPv4           TLSOptions
+	TLSIPv6           TLSOptions
+	DoHURL            string // RFC 8484 URL template, e.g. https://dns.google/dns-query{?dns}
+	DoHMethod         string // "post" or "get"
+	TCPFallback       bool   // Retry truncated UDP responses over TCP
+	EDNS              EDNSOptions
+	ClientSubnet      string // EDNS Client Subnet (RFC 7871) sent with every query
+	DNSSECValidate    bool   // Validate answers up to a trust anchor
+	TrustAnchorFile   string // DS/DNSKEY anchors in zone file format; empty uses the root KSKs
+	Trace             bool   // Resolve iteratively from the root instead of using the resolver
+	RootHintsFile     string // Root server NS/A/AAAA records; empty uses the built-in hints
+	NameserverPort    int    // Port of root and delegated nameservers queried directly; 0 uses 53
+	ResolverPort      int    // Plain DNS port of the resolver for follow-up lookups; 0 uses DNSPort for udp/tcp queries, 53 otherwise
+	Authoritative     bool   // Also query every authoritative server directly and compare
+	ZoneHealth        bool   // Check SOA serial/MNAME/RNAME consistency across nameservers
+	Delegation        bool   // Compare parent and child NS sets, glue and lame servers
+	PSLFile           string // Public Suffix List file; empty uses the embedded list
+	ZoneCut           string // How the NS fallback finds the zone: "psl" or "soa"
+	FollowCNAME       bool   // Query for the rest of CNAME chains the server left incomplete
+	MaxCNAMEDepth     int    // CNAME hops allowed before a chain is reported as too long; 0 uses DefaultMaxCNAMEDepth
+	ProbeENT          bool   // Query type ANY to tell NODATA from empty non-terminals without NSEC
+
+	// TSIG signing (RFC 8945)
+	TSIG     TSIGKey   // Default key for queries and transfers; empty Name disables TSIG
+	TSIGKeys []TSIGKey // Keys from --tsig-file that CSV rows can select by name
+
+	// Zone transfers (AXFR/IXFR)
+	ZoneFileDir string // Directory to write transferred zones to; empty disables
+}
+
+// EDNSOptions controls the EDNS0 OPT record added to outgoing queries
+type EDNSOptions struct {
+	Enabled bool
+	UDPSize uint16 // Advertised UDP payload size
+	DO      bool   // DNSSEC OK bit
+}
+
+// DefaultEDNS returns the EDNS settings used when no flags are given
+func DefaultEDNS() EDNSOptions {
+	return EDNSOptions{Enabled: true, UDPSize: DefaultEDNSBufSize}
+}
+
+// TLSOptions holds per-server settings for encrypted transports
+type TLSOptions struct {
+	ServerName string // SNI / certificate name; defaults to the server address
+	SPKIPin    string // Base64 SHA-256 of the server's SubjectPublicKeyInfo
+}
+
+// TSIGKey is a shared secret used to sign messages (RFC 8945)
+type TSIGKey struct {
+	Name      string // Fully qualified key name
+	Algorithm string // e.g. "hmac-sha256."
+	Secret    string // Base64-encoded secret
+}
+
+// tsigAlgorithms lists the supported TSIG algorithms by short name
+var tsigAlgorithms = map[string]string{
+	"hmac-sha1":   "hmac-sha1.",
+	"hmac-sha224": "hmac-sha224.",
+	"hmac-sha256": "hmac-sha256.",
+	"hmac-sha384": "hmac-sha384.",
+	"hmac-sha512": "hmac-sha512.",
+}
+
+// ParseTSIGKey parses a key in dig -y format: [algorithm:]name:secret.
+// The algorithm defaults to hmac-sha256.
+func ParseTSIGKey(s string) (TSIGKey, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"hmac-sha256"}, parts...)
+	}
+	if len(parts) != 3 {
+		return TSIGKey{}, fmt.Errorf("invalid TSIG key '%s': use [algorithm:]name:secret", s)
+	}
+
+	return NewTSIGKey(parts[1], parts[0], parts[2])
+}
+
+// NewTSIGKey validates and normalizes a TSIG key
+func NewTSIGKey(name, algorithm, secret string) (TSIGKey, error) {
+	alg, ok := tsigAlgorithms[strings.TrimSuffix(strings.ToLower(algorithm), ".")]
+	if !ok {
+		return TSIGKey{}, fmt.Errorf("unsupported TSIG algorithm '%s'", algorithm)
+	}
+	if name == "" {
+		return TSIGKey{}, errors.New("TSIG key name cannot be empty")
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil || secret == "" {
+		return TSIGKey{}, fmt.Errorf("invalid TSIG secret for key '%s': must be base64", name)
+	}
+
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return TSIGKey{Name: name, Algorithm: alg, Secret: secret}, nil
+}
+
+// LoadTSIGKeys reads TSIG keys from a BIND-style key file:
+//
+//	key "name" {
+//		algorithm hmac-sha256;
+//		secret "base64";
+//	};
+func LoadTSIGKeys(path string) ([]TSIGKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSIG key file: %w", err)
+	}
+
+	tokens := tokenizeKeyFile(string(data))
+	var keys []TSIGKey
+	for i := 0; i < len(tokens); {
+		// Each statement is: key <name> { <field> <value> ; ... } ;
+		if tokens[i] != "key" || i+2 >= len(tokens) || tokens[i+2] != "{" {
+			return nil, fmt.Errorf("%s: expected 'key \"name\" {' near '%s'", path, tokens[i])
+		}
+		name := tokens[i+1]
+		i += 3
+
+		fields := make(map[string]string)
+		for i < len(tokens) && tokens[i] != "}" {
+			if i+2 >= len(tokens) || tokens[i+2] != ";" {
+				return nil, fmt.Errorf("%s: malformed statement '%s' in key '%s'", path, tokens[i], name)
+			}
+			fields[strings.ToLower(tokens[i])] = tokens[i+1]
+			i += 3
+		}
+		if i >= len(tokens) {
+			return nil, fmt.Errorf("%s: unterminated key '%s'", path, name)
+		}
+		i++ // "}"
+		if i < len(tokens) && tokens[i] == ";" {
+			i++
+		}
+
+		key, err := NewTSIGKey(name, fields["algorithm"], fields["secret"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no TSIG keys found", path)
+	}
+	return keys, nil
+}
+
+// tokenizeKeyFile splits a key file into words, quoted strings and the
+// punctuation '{', '}' and ';', dropping #, // and /* */ comments
+func tokenizeKeyFile(text string) []string {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#' || strings.HasPrefix(text[i:], "//"):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				end = len(text) - i - 1
+			}
+			tokens = append(tokens, text[i+1:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(text) && !strings.ContainsRune(" \t\r\n{};\"", rune(text[i])) {
+				i++
+			}
+			tokens = append(tokens, text[start:i])
+		}
+	}
+	return tokens
+}
+
+// LookupTSIGKey finds a configured key by name
+func (cfg Config) LookupTSIGKey(name string) (TSIGKey, bool) {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	if cfg.TSIG.Name == name {
+		return cfg.TSIG, true
+	}
+	for _, key := range cfg.TSIGKeys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return TSIGKey{}, false
 }
 
 // Validate checks if configuration is valid
@@ -31,6 +241,14 @@ func Validate(cfg Config) error {
 		return errors.New("DNS port must be between 1 and 65535")
 	}
 
+	if cfg.NameserverPort < 0 || cfg.NameserverPort > 65535 {
+		return errors.New("nameserver port must be between 1 and 65535, or 0 for the default")
+	}
+
+	if cfg.ResolverPort < 0 || cfg.ResolverPort > 65535 {
+		return errors.New("resolver port must be between 1 and 65535, or 0 for the default")
+	}
+
 	if cfg.Timeout <= 0 {
 		return errors.New("timeout must be positive")
 	}
@@ -45,14 +263,96 @@ func Validate(cfg Config) error {
 
 	// Validate transport override if specified
 	if cfg.TransportOverride != "" {
-		if cfg.TransportOverride != "tcp" && cfg.TransportOverride != "udp" {
-			return fmt.Errorf("transport override must be 'tcp' or 'udp', got '%s'", cfg.TransportOverride)
+		switch cfg.TransportOverride {
+		case "udp", "tcp", "dot", "doh", "doq":
+		default:
+			return fmt.Errorf("transport override must be 'udp', 'tcp', 'dot', 'doh' or 'doq', got '%s'", cfg.TransportOverride)
 		}
+		if cfg.TransportOverride == "doh" && cfg.DoHURL == "" {
+			return errors.New("transport override 'doh' requires an https:// URL in --dns")
+		}
+	}
+
+	if cfg.EDNS.Enabled && cfg.EDNS.UDPSize < MinEDNSBufSize {
+		return fmt.Errorf("EDNS buffer size must be at least %d", MinEDNSBufSize)
+	}
+
+	if cfg.ClientSubnet != "" {
+		if err := ValidateClientSubnet(cfg.ClientSubnet); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DoHMethod != "" && cfg.DoHMethod != "post" && cfg.DoHMethod != "get" {
+		return fmt.Errorf("DoH method must be 'post' or 'get', got '%s'", cfg.DoHMethod)
+	}
+
+	if cfg.ZoneCut != "" && cfg.ZoneCut != ZoneCutPSL && cfg.ZoneCut != ZoneCutSOA {
+		return fmt.Errorf("zone cut mode must be '%s' or '%s', got '%s'", ZoneCutPSL, ZoneCutSOA, cfg.ZoneCut)
+	}
+
+	if cfg.MaxCNAMEDepth < 0 || cfg.MaxCNAMEDepth > MaxCNAMEDepthLimit {
+		return fmt.Errorf("CNAME depth must be between 1 and %d, or 0 for the default of %d", MaxCNAMEDepthLimit, DefaultMaxCNAMEDepth)
+	}
+
+	if err := validateSPKIPin(cfg.TLSIPv4.SPKIPin); err != nil {
+		return err
+	}
+	if err := validateSPKIPin(cfg.TLSIPv6.SPKIPin); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateClientSubnet checks that a client subnet is an IP address or CIDR prefix
+func ValidateClientSubnet(subnet string) error {
+	if strings.Contains(subnet, "/") {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf("invalid client subnet '%s': %w", subnet, err)
+		}
+		return nil
+	}
+
+	if net.ParseIP(subnet) == nil {
+		return fmt.Errorf("invalid client subnet '%s': not an IP address or CIDR prefix", subnet)
+	}
+
+	return nil
+}
+
+// validateSPKIPin checks that a pin is a base64-encoded SHA-256 digest
+func validateSPKIPin(pin string) error {
+	if pin == "" {
+		return nil
+	}
+
+	digest, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("invalid SPKI pin '%s': must be a base64-encoded SHA-256 digest", pin)
 	}
 
 	return nil
 }
 
+const (
+	DefaultDNSPort = 53
+	DefaultDoTPort = 853
+	DefaultDoQPort = 853
+
+	// DefaultEDNSBufSize follows the DNS Flag Day 2020 recommendation
+	DefaultEDNSBufSize = 1232
+	MinEDNSBufSize     = 512
+
+	// DefaultMaxCNAMEDepth is the longest CNAME chain followed before giving up
+	DefaultMaxCNAMEDepth = 8
+	MaxCNAMEDepthLimit   = 32
+
+	// Ways of finding the zone whose NS records are looked up
+	ZoneCutPSL = "psl" // Registrable domain from the Public Suffix List
+	ZoneCutSOA = "soa" // Walk SOA queries up to the zone apex
+)
+
 const (
 	MinWorkers         = 1
 	MaxWorkers         = 50
@@ -94,22 +394,40 @@ func CalculateOptimalWorkers(queryCount int) int {
 	return workers
 }
 
-// ParseDNSServers parses DNS server arguments
-func ParseDNSServers(args ...string) (ipv4Server string, ipv4Port int, ipv6Server string, ipv6Port int, err error) {
+// ParseDNSServers parses DNS server arguments. Up to two IP servers (ipv4 and ipv6)
+// may be given, plus at most one https:// URL used for DNS-over-HTTPS queries.
+func ParseDNSServers(args ...string) (ipv4Server string, ipv4Port int, ipv6Server string, ipv6Port int, dohURL string, err error) {
 	ipv4Port = 53
 	ipv6Port = 53
 
+	// Pull out DoH URLs before handling plain server addresses
+	var servers []string
+	for _, arg := range args {
+		if !isDoHURL(arg) {
+			servers = append(servers, arg)
+			continue
+		}
+		if dohURL != "" {
+			return "", 0, "", 0, "", errors.New("too many DoH URLs (max 1)")
+		}
+		if err := validateDoHURL(arg); err != nil {
+			return "", 0, "", 0, "", fmt.Errorf("invalid DoH URL '%s': %w", arg, err)
+		}
+		dohURL = arg
+	}
+	args = servers
+
 	if len(args) == 0 {
-		return "", 53, "", 53, nil
+		return "", 53, "", 53, dohURL, nil
 	}
 
 	if len(args) > 2 {
-		return "", 0, "", 0, errors.New("too many DNS server arguments (max 2: ipv4 and ipv6)")
+		return "", 0, "", 0, "", errors.New("too many DNS server arguments (max 2: ipv4 and ipv6)")
 	}
 
 	server1, port1, err := parseServerAddress(args[0])
 	if err != nil {
-		return "", 0, "", 0, fmt.Errorf("invalid DNS server '%s': %w", args[0], err)
+		return "", 0, "", 0, "", fmt.Errorf("invalid DNS server '%s': %w", args[0], err)
 	}
 
 	isIPv6_1 := isIPv6Address(server1)
@@ -126,12 +444,12 @@ func ParseDNSServers(args ...string) (ipv4Server string, ipv4Port int, ipv6Serve
 			ipv6Server = server1
 			ipv6Port = port1
 		}
-		return ipv4Server, ipv4Port, ipv6Server, ipv6Port, nil
+		return ipv4Server, ipv4Port, ipv6Server, ipv6Port, dohURL, nil
 	}
 
 	server2, port2, err := parseServerAddress(args[1])
 	if err != nil {
-		return "", 0, "", 0, fmt.Errorf("invalid DNS server '%s': %w", args[1], err)
+		return "", 0, "", 0, "", fmt.Errorf("invalid DNS server '%s': %w", args[1], err)
 	}
 
 	isIPv6_2 := isIPv6Address(server2)
@@ -160,7 +478,23 @@ func ParseDNSServers(args ...string) (ipv4Server string, ipv4Port int, ipv6Serve
 		fmt.Println("Warning: Both DNS servers are IPv6, using first for IPv4 queries as well")
 	}
 
-	return ipv4Server, ipv4Port, ipv6Server, ipv6Port, nil
+	return ipv4Server, ipv4Port, ipv6Server, ipv6Port, dohURL, nil
+}
+
+// isDoHURL reports whether a server argument is a DNS-over-HTTPS URL
+func isDoHURL(input string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(input)), "https://")
+}
+
+func validateDoHURL(input string) error {
+	u, err := url.Parse(strings.Replace(input, "{?dns}", "", 1))
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
 }
 
 func parseServerAddress(input string) (string, int, error) {
@@ -230,3 +564,34 @@ func isIPv6Address(ip string) bool {
 	}
 	return parsedIP.To4() == nil
 }
+
+// ParseTLSOptions builds per-server TLS options from space-separated server names
+// and SPKI pins. The first value applies to the IPv4 server and the second to the
+// IPv6 server; a single value applies to both.
+func ParseTLSOptions(names string, pins string) (ipv4 TLSOptions, ipv6 TLSOptions, err error) {
+	nameList := strings.Fields(names)
+	pinList := strings.Fields(pins)
+
+	if len(nameList) > 2 {
+		return ipv4, ipv6, errors.New("too many TLS server names (max 2: ipv4 and ipv6)")
+	}
+	if len(pinList) > 2 {
+		return ipv4, ipv6, errors.New("too many SPKI pins (max 2: ipv4 and ipv6)")
+	}
+
+	ipv4.ServerName, ipv6.ServerName = pickPerServer(nameList)
+	ipv4.SPKIPin, ipv6.SPKIPin = pickPerServer(pinList)
+
+	return ipv4, ipv6, nil
+}
+
+func pickPerServer(values []string) (string, string) {
+	switch len(values) {
+	case 0:
+		return "", ""
+	case 1:
+		return values[0], values[0]
+	default:
+		return values[0], values[1]
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// validConfig returns the smallest configuration that passes Validate
+func validConfig() Config {
+	return Config{
+		DNSServerIPv4: "127.0.0.1",
+		DNSPort:       DefaultDNSPort,
+		Timeout:       time.Second,
+		WorkerCount:   MinWorkers,
+	}
+}
+
+func TestValidateMaxCNAMEDepth(t *testing.T) {
+	tests := []struct {
+		depth   int
+		wantErr bool
+	}{
+		{0, false}, // Default
+		{1, false},
+		{MaxCNAMEDepthLimit, false},
+		{-1, true},
+		{MaxCNAMEDepthLimit + 1, true},
+	}
+
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.MaxCNAMEDepth = tt.depth
+		if err := Validate(cfg); (err != nil) != tt.wantErr {
+			t.Errorf("depth %d: err = %v, want error %v", tt.depth, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTokenizeKeyFile(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{`key "k1" { secret "c2VjcmV0"; };`, []string{"key", "k1", "{", "secret", "c2VjcmV0", ";", "}", ";"}},
+		{"key k1{algorithm hmac-sha256;}", []string{"key", "k1", "{", "algorithm", "hmac-sha256", ";", "}"}},
+		{"# comment\nkey // comment\n/* block\ncomment */ k1", []string{"key", "k1"}},
+		{`"quoted ; { }"`, []string{"quoted ; { }"}},
+		{`"unterminated`, []string{"unterminated"}},
+		{"key /* unterminated", []string{"key"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := tokenizeKeyFile(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeKeyFile(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTSIGKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    []TSIGKey
+		wantErr bool
+	}{
+		{
+			name: "two keys",
+			file: `// rndc-confgen output
+key "Transfer.Example" {
+	algorithm hmac-sha512;
+	secret "c2VjcmV0";
+};
+key update {
+	secret "b3RoZXI=";  # fields in any order
+	algorithm HMAC-SHA256.;
+}`,
+			want: []TSIGKey{
+				{Name: "transfer.example.", Algorithm: "hmac-sha512.", Secret: "c2VjcmV0"},
+				{Name: "update.", Algorithm: "hmac-sha256.", Secret: "b3RoZXI="},
+			},
+		},
+		{name: "empty file", file: "# nothing here\n", wantErr: true},
+		{name: "missing brace", file: `key "k1" algorithm hmac-sha256;`, wantErr: true},
+		{name: "missing semicolon", file: `key "k1" { algorithm hmac-sha256 }`, wantErr: true},
+		{name: "unterminated key", file: `key "k1" { algorithm hmac-sha256;`, wantErr: true},
+		{name: "missing algorithm", file: `key "k1" { secret "c2VjcmV0"; };`, wantErr: true},
+		{name: "bad secret", file: `key "k1" { algorithm hmac-sha256; secret "not base64!"; };`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tsig.key")
+			if err := os.WriteFile(path, []byte(tt.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := LoadTSIGKeys(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys = %+v, want %+v", keys, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadTSIGKeys(filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -2,9 +2,13 @@ module dns_query_utility
 
 go 1.25.7
 
-require github.com/miekg/dns v1.1.72
+require (
+	github.com/miekg/dns v1.1.72
+	github.com/quic-go/quic-go v0.59.1
+)
 
 require (
+	golang.org/x/crypto v0.46.0 // indirect
 	golang.org/x/mod v0.31.0 // indirect
 	golang.org/x/net v0.48.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
@@ -16,14 +16,14 @@ import (
 
 func main() {
 	// Parse arguments with new flags
-	csvFile, dnsArg, outputFile, formatArg, timeoutArg, retryArg, workersArg, transportOverride, queryAll, showHelp := parseArgs(os.Args[1:])
+	opts := parseArgs(os.Args[1:])
 
-	if showHelp {
+	if opts.showHelp {
 		printUsage()
 		os.Exit(0)
 	}
 
-	if csvFile == "" {
+	if opts.csvFile == "" {
 		fmt.Println("Error: CSV file not specified")
 		fmt.Println("\nUsage: dns_query_utility <csv_file> [options]")
 		fmt.Println("Run 'dns_query_utility --help' for more information")
@@ -34,12 +34,12 @@ func main() {
 
 	// Parse DNS servers
 	var dnsServers []string
-	if dnsArg != "" {
-		dnsServers = strings.Fields(dnsArg)
-		fmt.Printf("DNS Server(s): %v\n", dnsArg)
+	if opts.dnsArg != "" {
+		dnsServers = strings.Fields(opts.dnsArg)
+		fmt.Printf("DNS Server(s): %v\n", opts.dnsArg)
 	}
 
-	ipv4Server, ipv4Port, ipv6Server, ipv6Port, err := config.ParseDNSServers(dnsServers...)
+	ipv4Server, ipv4Port, ipv6Server, ipv6Port, dohURL, err := config.ParseDNSServers(dnsServers...)
 	if err != nil {
 		fmt.Printf("\nError parsing DNS servers: %v\n", err)
 		os.Exit(1)
@@ -56,12 +56,66 @@ func main() {
 		fmt.Println("Using custom DNS servers")
 	}
 
+	// Parse per-server TLS options for DoT
+	tlsIPv4, tlsIPv6, err := config.ParseTLSOptions(opts.tlsServerName, opts.tlsPin)
+	if err != nil {
+		fmt.Printf("\nError parsing TLS options: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Load TSIG keys and pick the default key: either given inline in dig -y
+	// format or named from the key file
+	var tsigKeys []config.TSIGKey
+	if opts.tsigFile != "" {
+		tsigKeys, err = config.LoadTSIGKeys(opts.tsigFile)
+		if err != nil {
+			fmt.Printf("\nError loading TSIG keys: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var tsigKey config.TSIGKey
+	if strings.Contains(opts.tsigKey, ":") {
+		tsigKey, err = config.ParseTSIGKey(opts.tsigKey)
+		if err != nil {
+			fmt.Printf("\nError parsing TSIG key: %v\n", err)
+			os.Exit(1)
+		}
+	} else if opts.tsigKey != "" {
+		var ok bool
+		tsigKey, ok = config.Config{TSIGKeys: tsigKeys}.LookupTSIGKey(opts.tsigKey)
+		if !ok {
+			fmt.Printf("\nError: TSIG key '%s' not found (pass a key file with --tsig-file)\n", opts.tsigKey)
+			os.Exit(1)
+		}
+	}
+
+	// DoH uses POST unless GET is requested
+	dohMethod := "post"
+	if opts.dohMethod != "" {
+		dohMethod = strings.ToLower(opts.dohMethod)
+	}
+
+	// Parse EDNS options
+	edns := config.DefaultEDNS()
+	if opts.noEDNS {
+		edns.Enabled = false
+	}
+	if opts.ednsBufSize != "" {
+		size, err := strconv.ParseUint(opts.ednsBufSize, 10, 16)
+		if err != nil || size < config.MinEDNSBufSize {
+			fmt.Printf("Error: invalid EDNS buffer size '%s' (must be %d-65535)\n", opts.ednsBufSize, config.MinEDNSBufSize)
+			os.Exit(1)
+		}
+		edns.UDPSize = uint16(size)
+	}
+	edns.DO = opts.ednsDO
+
 	// Parse timeout
 	timeout := 5 * time.Second
-	if timeoutArg != "" {
-		t, err := time.ParseDuration(timeoutArg)
+	if opts.timeoutArg != "" {
+		t, err := time.ParseDuration(opts.timeoutArg)
 		if err != nil {
-			fmt.Printf("Error: invalid timeout '%s' (use format like 5s, 500ms, 1m)\n", timeoutArg)
+			fmt.Printf("Error: invalid timeout '%s' (use format like 5s, 500ms, 1m)\n", opts.timeoutArg)
 			os.Exit(1)
 		}
 		if t <= 0 {
@@ -73,38 +127,83 @@ func main() {
 
 	// Parse retry count
 	retryCount := 2
-	if retryArg != "" {
-		rc, err := strconv.Atoi(retryArg)
+	if opts.retryArg != "" {
+		rc, err := strconv.Atoi(opts.retryArg)
 		if err != nil || rc < 0 || rc > 10 {
-			fmt.Printf("Error: invalid retry count '%s' (must be 0-10)\n", retryArg)
+			fmt.Printf("Error: invalid retry count '%s' (must be 0-10)\n", opts.retryArg)
 			os.Exit(1)
 		}
 		retryCount = rc
 	}
 
-	// Parse CSV
-	specs, err := parser.ParseCSV(csvFile)
+	// Parse the port of nameservers queried directly
+	nameserverPort := 0
+	if opts.nameserverPort != "" {
+		port, err := strconv.Atoi(opts.nameserverPort)
+		if err != nil || port < 1 || port > 65535 {
+			fmt.Printf("Error: invalid nameserver port '%s' (must be 1-65535)\n", opts.nameserverPort)
+			os.Exit(1)
+		}
+		nameserverPort = port
+	}
+
+	// Parse the plain DNS port of the resolver used for follow-up lookups
+	resolverPort := 0
+	if opts.resolverPort != "" {
+		port, err := strconv.Atoi(opts.resolverPort)
+		if err != nil || port < 1 || port > 65535 {
+			fmt.Printf("Error: invalid resolver port '%s' (must be 1-65535)\n", opts.resolverPort)
+			os.Exit(1)
+		}
+		resolverPort = port
+	}
+
+	// Parse CNAME chain depth
+	maxCNAMEDepth := config.DefaultMaxCNAMEDepth
+	if opts.maxCNAMEDepth != "" {
+		depth, err := strconv.Atoi(opts.maxCNAMEDepth)
+		if err != nil || depth < 1 || depth > config.MaxCNAMEDepthLimit {
+			fmt.Printf("Error: invalid CNAME depth '%s' (must be 1-%d)\n", opts.maxCNAMEDepth, config.MaxCNAMEDepthLimit)
+			os.Exit(1)
+		}
+		maxCNAMEDepth = depth
+	}
+
+	// Parse CSV: update operations in --update mode, queries otherwise
+	parseCSV := parser.ParseCSV
+	if opts.update {
+		if opts.queryAll {
+			fmt.Println("Error: --update cannot be combined with --query-all")
+			os.Exit(1)
+		}
+		parseCSV = parser.ParseUpdateCSV
+	}
+	specs, err := parseCSV(opts.csvFile)
 	if err != nil {
 		fmt.Printf("\nError parsing CSV: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Successfully parsed %d queries from CSV\n", len(specs))
+	if opts.update {
+		fmt.Printf("Successfully parsed %d update operations from CSV\n", len(specs))
+	} else {
+		fmt.Printf("Successfully parsed %d queries from CSV\n", len(specs))
+	}
 
 	// Check for ANY + --query-all conflict
-	// checkForANYWithQueryAll(specs, queryAll)
+	// checkForANYWithQueryAll(specs, opts.queryAll)
 
 	// Apply overrides BEFORE calculating workers
 	originalCount := len(specs)
 
 	// 1. Apply transport override
-	if transportOverride != "" {
-		specs = applyTransportOverride(specs, transportOverride)
-		fmt.Printf("✓ Transport override: All queries will use %s\n", strings.ToUpper(transportOverride))
+	if opts.transportOverride != "" {
+		specs = applyTransportOverride(specs, opts.transportOverride)
+		fmt.Printf("✓ Transport override: All queries will use %s\n", strings.ToUpper(opts.transportOverride))
 	}
 
 	// 2. Expand to all query types if requested
-	if queryAll {
+	if opts.queryAll {
 		specs = expandToAllTypes(specs)
 		fmt.Printf("✓ Query-all mode: Expanded %d domains to %d queries (all record types)\n", originalCount, len(specs))
 		fmt.Printf("✓ Output will be consolidated (one record per domain)\n")
@@ -112,10 +211,10 @@ func main() {
 
 	// Auto-calculate or parse workers
 	var workerCount int
-	if workersArg != "" {
-		wc, err := strconv.Atoi(workersArg)
+	if opts.workersArg != "" {
+		wc, err := strconv.Atoi(opts.workersArg)
 		if err != nil || wc < config.MinWorkers || wc > config.AbsoluteMaxWorkers {
-			fmt.Printf("Error: invalid worker count '%s' (must be %d-%d)\n", workersArg, config.MinWorkers, config.AbsoluteMaxWorkers)
+			fmt.Printf("Error: invalid worker count '%s' (must be %d-%d)\n", opts.workersArg, config.MinWorkers, config.AbsoluteMaxWorkers)
 			os.Exit(1)
 		}
 		workerCount = wc
@@ -132,8 +231,32 @@ func main() {
 		Timeout:           timeout,
 		RetryCount:        retryCount,
 		WorkerCount:       workerCount,
-		TransportOverride: transportOverride,
-		QueryAllTypes:     queryAll,
+		TransportOverride: opts.transportOverride,
+		QueryAllTypes:     opts.queryAll,
+		TLSIPv4:           tlsIPv4,
+		TLSIPv6:           tlsIPv6,
+		DoHURL:            dohURL,
+		DoHMethod:         dohMethod,
+		TCPFallback:       !opts.noTCPFallback,
+		EDNS:              edns,
+		ClientSubnet:      opts.clientSubnet,
+		DNSSECValidate:    opts.dnssecValidate,
+		TrustAnchorFile:   opts.trustAnchorFile,
+		Trace:             opts.trace,
+		RootHintsFile:     opts.rootHintsFile,
+		NameserverPort:    nameserverPort,
+		ResolverPort:      resolverPort,
+		Authoritative:     opts.authoritative,
+		ZoneHealth:        opts.zoneHealth,
+		Delegation:        opts.delegation,
+		PSLFile:           opts.pslFile,
+		ZoneCut:           strings.ToLower(opts.zoneCut),
+		FollowCNAME:       opts.followCNAME,
+		ProbeENT:          opts.probeENT,
+		MaxCNAMEDepth:     maxCNAMEDepth,
+		TSIG:              tsigKey,
+		TSIGKeys:          tsigKeys,
+		ZoneFileDir:       opts.zoneFileDir,
 	}
 
 	if err := config.Validate(cfg); err != nil {
@@ -141,14 +264,106 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Every per-row TSIG key must be known before any query runs
+	for _, spec := range specs {
+		if spec.TSIGKey == "" || strings.EqualFold(spec.TSIGKey, "off") {
+			continue
+		}
+		if _, ok := cfg.LookupTSIGKey(spec.TSIGKey); !ok {
+			fmt.Printf("Configuration error: CSV row for %s uses unknown TSIG key '%s'\n", spec.Domain, spec.TSIGKey)
+			os.Exit(1)
+		}
+	}
+
+	// Load trust anchors up front so a bad file fails before any query runs
+	if cfg.DNSSECValidate {
+		if _, err := query.LoadTrustAnchors(cfg.TrustAnchorFile); err != nil {
+			fmt.Printf("Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if _, err := query.LoadPublicSuffixList(cfg.PSLFile); err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Trace {
+		if _, err := query.LoadRootHints(cfg.RootHintsFile); err != nil {
+			fmt.Printf("Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var takeoverSignatures []query.TakeoverSignature
+	if opts.takeoverAudit {
+		takeoverSignatures, err = query.LoadTakeoverSignatures(opts.takeoverSignatures)
+		if err != nil {
+			fmt.Printf("Configuration error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if opts.takeoverSignatures != "" {
+		fmt.Println("Error: --takeover-signatures requires --takeover-audit")
+		os.Exit(1)
+	}
+
 	fmt.Printf("\nDNS Configuration:\n")
 	fmt.Printf("  IPv4 Server:   %s:%d\n", cfg.DNSServerIPv4, ipv4Port)
 	fmt.Printf("  IPv6 Server:   %s:%d\n", cfg.DNSServerIPv6, ipv6Port)
+	if cfg.DoHURL != "" {
+		fmt.Printf("  DoH URL:       %s (%s)\n", cfg.DoHURL, strings.ToUpper(cfg.DoHMethod))
+	}
+	if cfg.EDNS.Enabled {
+		fmt.Printf("  EDNS:          %d bytes, DO=%v\n", cfg.EDNS.UDPSize, cfg.EDNS.DO)
+	} else {
+		fmt.Printf("  EDNS:          disabled\n")
+	}
+	if cfg.ClientSubnet != "" {
+		fmt.Printf("  Client Subnet: %s\n", cfg.ClientSubnet)
+	}
+	if cfg.TSIG.Name != "" {
+		fmt.Printf("  TSIG:          %s (%s)\n", cfg.TSIG.Name, strings.TrimSuffix(cfg.TSIG.Algorithm, "."))
+	}
+	if cfg.DNSSECValidate {
+		anchor := "root KSKs (built-in)"
+		if cfg.TrustAnchorFile != "" {
+			anchor = cfg.TrustAnchorFile
+		}
+		fmt.Printf("  DNSSEC:        validating (trust anchor: %s)\n", anchor)
+	}
+	if cfg.Trace {
+		hints := "built-in"
+		if cfg.RootHintsFile != "" {
+			hints = cfg.RootHintsFile
+		}
+		fmt.Printf("  Trace:         iterative from root (hints: %s)\n", hints)
+	}
+	if cfg.ZoneCut == config.ZoneCutSOA {
+		fmt.Printf("  NS Zone Cut:   SOA walk\n")
+	} else if cfg.PSLFile != "" {
+		fmt.Printf("  NS Zone Cut:   public suffix list (%s)\n", cfg.PSLFile)
+	}
+	if cfg.Authoritative {
+		fmt.Printf("  Authoritative: querying every authoritative server (RD=0)\n")
+	}
+	if cfg.ZoneHealth {
+		fmt.Printf("  Zone Health:   SOA consistency across nameservers\n")
+	}
+	if cfg.Delegation {
+		fmt.Printf("  Delegation:    parent/child NS, glue and lame server checks\n")
+	}
+	if cfg.FollowCNAME {
+		fmt.Printf("  CNAME Chains:  following incomplete chains (max %d hops)\n", cfg.MaxCNAMEDepth)
+	}
+	if opts.takeoverAudit {
+		source := "built-in"
+		if opts.takeoverSignatures != "" {
+			source = opts.takeoverSignatures
+		}
+		fmt.Printf("  Takeover:      auditing CNAME targets (%d signatures, %s)\n", len(takeoverSignatures), source)
+	}
 	fmt.Printf("  Timeout:       %v\n", cfg.Timeout)
 	fmt.Printf("  Retry Count:   %d\n", cfg.RetryCount)
 	fmt.Printf("  Query Count:   %d\n", len(specs))
 	fmt.Printf("  Workers:       %d", cfg.WorkerCount)
-	if workersArg != "" {
+	if opts.workersArg != "" {
 		fmt.Printf(" (manual override)")
 	} else {
 		fmt.Printf(" (auto-scaled)")
@@ -165,10 +380,16 @@ func main() {
 
 	fmt.Printf("\nAll queries completed in %v\n", totalDuration)
 
+	// Check the aliases found by the queries for takeover risks
+	var takeover *result.TakeoverReport
+	if opts.takeoverAudit {
+		takeover = query.AuditTakeover(results, takeoverSignatures, cfg)
+	}
+
 	// Determine output format
 	format := output.FormatJSON
-	if formatArg != "" {
-		switch strings.ToLower(formatArg) {
+	if opts.formatArg != "" {
+		switch strings.ToLower(opts.formatArg) {
 		case "csv":
 			format = output.FormatCSV
 		case "json":
@@ -176,26 +397,26 @@ func main() {
 		case "all":
 			format = output.FormatAll
 		default:
-			fmt.Printf("Error: unknown format '%s' (use: csv, json, all)\n", formatArg)
+			fmt.Printf("Error: unknown format '%s' (use: csv, json, all)\n", opts.formatArg)
 			os.Exit(1)
 		}
 	}
 
 	// Determine output file name
-	if outputFile == "" {
-		outputFile = "result"
+	if opts.outputFile == "" {
+		opts.outputFile = "result"
 	}
 
 	// Build metadata
 	metadata := buildMetadata(results, totalDuration, cfg, ipv4Server, ipv4Port, ipv6Server, ipv6Port)
 
 	// Generate output file(s) - consolidate if using --query-all
-	consolidate := queryAll
+	consolidate := opts.queryAll
 
 	switch format {
 	case output.FormatJSON:
-		jsonPath := output.ChangeExtension(outputFile, ".json")
-		if err := output.WriteOutput(jsonPath, output.FormatJSON, results, metadata, consolidate); err != nil {
+		jsonPath := output.ChangeExtension(opts.outputFile, ".json")
+		if err := output.WriteOutput(jsonPath, output.FormatJSON, results, metadata, consolidate, opts.legacyRecords, takeover); err != nil {
 			fmt.Printf("\nError writing JSON file: %v\n", err)
 			os.Exit(1)
 		}
@@ -206,18 +427,18 @@ func main() {
 		}
 
 	case output.FormatCSV:
-		csvPath := output.ChangeExtension(outputFile, ".csv")
-		if err := output.WriteOutput(csvPath, output.FormatCSV, results, metadata, false); err != nil {
+		csvPath := output.ChangeExtension(opts.outputFile, ".csv")
+		if err := output.WriteOutput(csvPath, output.FormatCSV, results, metadata, false, false, nil); err != nil {
 			fmt.Printf("\nError writing CSV file: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("\n✓ CSV output written to: %s\n", csvPath)
 
 	case output.FormatAll:
-		jsonPath := output.ChangeExtension(outputFile, ".json")
-		csvPath := output.ChangeExtension(outputFile, ".csv")
+		jsonPath := output.ChangeExtension(opts.outputFile, ".json")
+		csvPath := output.ChangeExtension(opts.outputFile, ".csv")
 
-		if err := output.WriteOutput(jsonPath, output.FormatJSON, results, metadata, consolidate); err != nil {
+		if err := output.WriteOutput(jsonPath, output.FormatJSON, results, metadata, consolidate, opts.legacyRecords, takeover); err != nil {
 			fmt.Printf("\nError writing JSON file: %v\n", err)
 			os.Exit(1)
 		}
@@ -227,7 +448,7 @@ func main() {
 			fmt.Printf("\n✓ JSON output written to: %s\n", jsonPath)
 		}
 
-		if err := output.WriteOutput(csvPath, output.FormatCSV, results, metadata, false); err != nil {
+		if err := output.WriteOutput(csvPath, output.FormatCSV, results, metadata, false, false, nil); err != nil {
 			fmt.Printf("\nError writing CSV file: %v\n", err)
 			os.Exit(1)
 		}
@@ -245,15 +466,17 @@ func main() {
 	}
 
 	printSummary(results, totalDuration, cfg.WorkerCount)
+
+	if takeover != nil {
+		printTakeoverReport(takeover)
+	}
 }
 
 // applyTransportOverride overrides transport protocol for all queries
 func applyTransportOverride(specs []query.QuerySpec, transport string) []query.QuerySpec {
-	var overrideTransport query.Transport
-	if transport == "tcp" {
-		overrideTransport = query.TCP
-	} else {
-		overrideTransport = query.UDP
+	overrideTransport, err := query.ParseTransport(transport)
+	if err != nil {
+		return specs
 	}
 
 	for i := range specs {
@@ -278,8 +501,8 @@ func expandToAllTypes(specs []query.QuerySpec) []query.QuerySpec {
 	// Expand each domain to all query types
 	var expanded []query.QuerySpec
 	for _, spec := range domainMap {
-		// ExpandToAllTypes expects: domain, transport, ipVersion (3 args)
-		allTypeSpecs := query.ExpandToAllTypes(spec.Domain, spec.Transport, spec.IPVersion)
+		// ExpandToAllTypes keeps every per-row setting except the query type
+		allTypeSpecs := query.ExpandToAllTypes(spec)
 		expanded = append(expanded, allTypeSpecs...)
 	}
 
@@ -342,6 +565,7 @@ func buildMetadata(results []result.QueryResult, duration time.Duration, cfg con
 		QueriesPerSecond:  float64(len(results)) / duration.Seconds(),
 		DNSServerIPv4:     fmt.Sprintf("%s:%d", ipv4, ipv4Port),
 		DNSServerIPv6:     fmt.Sprintf("%s:%d", ipv6, ipv6Port),
+		DoHURL:            cfg.DoHURL,
 		WorkersUsed:       cfg.WorkerCount,
 		TimeoutSeconds:    cfg.Timeout.Seconds(),
 		RetryCount:        cfg.RetryCount,
@@ -350,21 +574,167 @@ func buildMetadata(results []result.QueryResult, duration time.Duration, cfg con
 
 func displayResults(results []result.QueryResult) {
 	for i, res := range results {
+		queryType := res.QueryType
+		if res.QueryClass != "" {
+			queryType = res.QueryClass + " " + queryType
+		}
 		fmt.Printf("%d. %s (type=%s transport=%s network=%s)\n",
-			i+1, res.Domain, res.QueryType, res.Transport, res.IPVersion)
+			i+1, res.Domain, queryType, res.Transport, res.IPVersion)
 
 		statusIcon := getStatusIcon(string(res.Status))
 		fmt.Printf("   Status:        %s %s\n", statusIcon, res.Status)
 		fmt.Printf("   Latency:       %.2fms\n", res.LatencyMs)
 		fmt.Printf("   Response Code: %d\n", res.ResponseCode)
+		if res.Header != nil {
+			fmt.Printf("   Header:        id %d, flags [%s], %d bytes\n", res.Header.ID, res.Header.Flags(), res.ResponseSize)
+		}
+		if res.MinTTL != nil {
+			fmt.Printf("   Min TTL:       %ds\n", *res.MinTTL)
+		}
+		if chain := res.CNAMEChain; chain != nil {
+			path := []string{chain.Hops[0].Name}
+			for _, hop := range chain.Hops {
+				path = append(path, fmt.Sprintf("%s (%ds)", hop.Target, hop.TTL))
+			}
+			fmt.Printf("   CNAME Chain:   %s\n", strings.Join(path, " -> "))
+			switch {
+			case chain.Loop:
+				fmt.Printf("                  ✗ loop back to %s\n", chain.Final)
+			case chain.TooLong:
+				fmt.Printf("                  ✗ more than the allowed %d hops\n", len(chain.Hops)-1)
+			case !chain.Complete && chain.Error != "":
+				fmt.Printf("                  ✗ incomplete: %s\n", chain.Error)
+			case !chain.Complete:
+				fmt.Printf("                  ⚠ incomplete: no records for %s in the answer\n", chain.Final)
+			case chain.FollowUps > 0:
+				fmt.Printf("                  ✓ completed by follow-up queries (%d)\n", chain.FollowUps)
+			}
+		}
+		if res.Negative != nil {
+			fmt.Printf("   Negative:      %s", res.Negative.Kind)
+			if res.Negative.NegativeTTL != nil {
+				fmt.Printf(", cached for %ds (SOA %s)", *res.Negative.NegativeTTL, res.Negative.Zone)
+			}
+			if res.Negative.Inconclusive {
+				fmt.Printf(" (inconclusive: ANY probe failed)")
+			}
+			fmt.Println()
+		}
 
 		// NEW: Display authoritative nameservers
 		if len(res.AuthoritativeNS) > 0 {
 			fmt.Printf("   Authority NS:  %v\n", res.AuthoritativeNS)
 		}
 
+		if res.Truncated {
+			if res.FallbackTransport != "" {
+				fmt.Printf("   Truncated:     yes (retried over %s)\n", strings.ToUpper(res.FallbackTransport))
+			} else {
+				fmt.Printf("   Truncated:     yes (partial answer, fallback disabled)\n")
+			}
+		}
+
+		if res.EDNS != nil {
+			fmt.Printf("   Server EDNS:   version %d, %d bytes\n", res.EDNS.Version, res.EDNS.UDPSize)
+		}
+
+		if res.NSID != "" {
+			fmt.Printf("   NSID:          %s\n", res.NSID)
+		}
+
+		for _, ede := range res.EDE {
+			fmt.Printf("   EDE:           %d %s", ede.Code, ede.Purpose)
+			if ede.Text != "" {
+				fmt.Printf(" (%s)", ede.Text)
+			}
+			fmt.Println()
+		}
+
+		if res.ECS != nil {
+			fmt.Printf("   Client Subnet: %s (scope /%d)\n", res.ECS.Subnet, res.ECS.ScopePrefix)
+		}
+
+		if res.DNSSEC != nil {
+			if res.DNSSEC.Reason != "" {
+				fmt.Printf("   DNSSEC:        %s (%s)\n", res.DNSSEC.Status, res.DNSSEC.Reason)
+			} else {
+				fmt.Printf("   DNSSEC:        %s\n", res.DNSSEC.Status)
+			}
+		}
+
+		for _, step := range res.Trace {
+			switch {
+			case step.Error != "":
+				fmt.Printf("   Trace:         %-14s %s (%s) error: %s\n", step.Zone, step.Server, step.Address, step.Error)
+			case step.Referral != "":
+				fmt.Printf("   Trace:         %-14s %s (%s) %.2fms -> %s\n", step.Zone, step.Server, step.Address, step.LatencyMs, step.Referral)
+			default:
+				fmt.Printf("   Trace:         %-14s %s (%s) %.2fms %s aa=%v\n", step.Zone, step.Server, step.Address, step.LatencyMs, step.Rcode, step.Authoritative)
+			}
+		}
+
+		if res.Authoritative != nil {
+			switch {
+			case res.Authoritative.Error != "":
+				fmt.Printf("   Auth Servers:  %s\n", res.Authoritative.Error)
+			case res.Authoritative.Consistent:
+				fmt.Printf("   Auth Servers:  %d consistent (zone %s)\n", len(res.Authoritative.Servers), res.Authoritative.Zone)
+			default:
+				fmt.Printf("   Auth Servers:  ✗ inconsistent (zone %s): %s\n", res.Authoritative.Zone, res.Authoritative.Reason)
+			}
+			for _, server := range res.Authoritative.Servers {
+				if server.Error != "" {
+					fmt.Printf("     %s (%s) error: %s\n", server.Server, server.Address, server.Error)
+				} else {
+					fmt.Printf("     %s (%s) %.2fms %s aa=%v %v\n", server.Server, server.Address, server.LatencyMs, server.Rcode, server.Authoritative, server.Answers)
+				}
+			}
+		}
+
+		if res.ZoneHealth != nil {
+			displayZoneHealth(res.ZoneHealth)
+		}
+
+		if res.Delegation != nil {
+			displayDelegation(res.Delegation)
+		}
+
+		if res.Transfer != nil {
+			fmt.Printf("   Transfer:      serial %d, %d records in %d messages", res.Transfer.Serial, res.Transfer.RecordCount, res.Transfer.Envelopes)
+			if res.Transfer.TSIGKey != "" {
+				fmt.Printf(", TSIG %s", res.Transfer.TSIGKey)
+			}
+			fmt.Println()
+			if res.Transfer.ZoneFile != "" {
+				fmt.Printf("   Zone File:     %s\n", res.Transfer.ZoneFile)
+			}
+		}
+
+		if res.Update != nil {
+			fmt.Printf("   Update:        %s %s (zone %s), verified: %v\n", res.Update.Action, res.Update.Record, res.Update.Zone, res.Update.Verified)
+			for _, record := range res.Update.Current {
+				fmt.Printf("   Now Served:    %s\n", record)
+			}
+		}
+
+		if res.TSIG != nil {
+			fmt.Printf("   TSIG:          %s, verified: %v", res.TSIG.Key, res.TSIG.Verified)
+			if res.TSIG.Error != "" {
+				fmt.Printf(" (%s)", res.TSIG.Error)
+			}
+			fmt.Println()
+		}
+
+		if res.ALPN != "" {
+			fmt.Printf("   ALPN:          %s (0-RTT: %v)\n", res.ALPN, res.Used0RTT)
+		}
+
 		switch res.Status {
 		case result.StatusSuccess:
+			if res.Transfer != nil {
+				// Transferred zones are too large to print; see the output file
+				break
+			}
 			if len(res.Records) > 0 {
 				fmt.Printf("   Records:       %v\n", res.Records)
 			}
@@ -397,6 +767,14 @@ func displayConsolidatedResults(consolidated []result.ConsolidatedResult) {
 			cr.Summary.TotalQueries, cr.Summary.Successful, cr.Summary.NoAnswer,
 			cr.Summary.Failed, cr.Summary.AverageLatencyMs)
 
+		if cr.ZoneHealth != nil {
+			displayZoneHealth(cr.ZoneHealth)
+		}
+
+		if cr.Delegation != nil {
+			displayDelegation(cr.Delegation)
+		}
+
 		fmt.Println("   Query Types:")
 		for qType, typeRes := range cr.QueryTypes {
 			statusIcon := getStatusIcon(string(typeRes.Status))
@@ -424,6 +802,34 @@ func displayConsolidatedResults(consolidated []result.ConsolidatedResult) {
 	}
 }
 
+func displayZoneHealth(health *result.ZoneHealth) {
+	switch {
+	case health.Error != "":
+		fmt.Printf("   Zone Health:   %s\n", health.Error)
+	case health.Healthy:
+		fmt.Printf("   Zone Health:   ✓ %s serial %v on %d servers\n", health.Zone, health.Serials, len(health.Servers))
+	default:
+		fmt.Printf("   Zone Health:   ✗ %s serials %v\n", health.Zone, health.Serials)
+		for _, issue := range health.Issues {
+			fmt.Printf("     - %s\n", issue)
+		}
+	}
+}
+
+func displayDelegation(delegation *result.DelegationResult) {
+	switch {
+	case delegation.Error != "":
+		fmt.Printf("   Delegation:    %s\n", delegation.Error)
+	case delegation.Consistent:
+		fmt.Printf("   Delegation:    ✓ %s from %s %v\n", delegation.Zone, delegation.Parent, delegation.ParentNS)
+	default:
+		fmt.Printf("   Delegation:    ✗ %s from %s\n", delegation.Zone, delegation.Parent)
+		for _, issue := range delegation.Issues {
+			fmt.Printf("     - %s\n", issue)
+		}
+	}
+}
+
 func printSummary(results []result.QueryResult, totalDuration time.Duration, workerCount int) {
 	fmt.Println("Summary:")
 	fmt.Println("========")
@@ -462,154 +868,230 @@ func printSummary(results []result.QueryResult, totalDuration time.Duration, wor
 	}
 }
 
-func parseArgs(args []string) (string, string, string, string, string, string, string, string, bool, bool) {
-	var csvFile string
-	var dnsArg string
-	var outputFile string
-	var formatArg string
-	var timeoutArg string
-	var retryArg string
-	var workersArg string
-	var transportOverride string
-	queryAll := false
-	showHelp := false
-
-	i := 0
-	for i < len(args) {
+// printTakeoverReport lists the findings of --takeover-audit, highest risk first
+func printTakeoverReport(report *result.TakeoverReport) {
+	fmt.Println("\nTakeover Audit:")
+	fmt.Println("===============")
+	fmt.Printf("Aliases Checked:  %d\n", report.Checked)
+	fmt.Printf("Findings:         %d\n", len(report.Findings))
+
+	icons := map[result.TakeoverRisk]string{
+		result.TakeoverHigh:          "✗",
+		result.TakeoverMedium:        "⚠",
+		result.TakeoverLow:           "?",
+		result.TakeoverIndeterminate: "…",
+	}
+	for _, finding := range report.Findings {
+		fmt.Printf("\n%s %s risk: %s\n", icons[finding.Risk], strings.ToUpper(string(finding.Risk)), finding.Domain)
+		fmt.Printf("   Chain:         %s\n", strings.Join(finding.Chain, " -> "))
+		if finding.Provider != "" {
+			fmt.Printf("   Provider:      %s\n", finding.Provider)
+		}
+		fmt.Printf("   Reason:        %s\n", finding.Reason)
+	}
+}
+
+// cliOptions holds the raw command-line arguments before they are validated
+type cliOptions struct {
+	csvFile            string
+	dnsArg             string
+	outputFile         string
+	formatArg          string
+	timeoutArg         string
+	retryArg           string
+	workersArg         string
+	transportOverride  string
+	tlsServerName      string
+	tlsPin             string
+	dohMethod          string
+	noTCPFallback      bool
+	ednsBufSize        string
+	ednsDO             bool
+	noEDNS             bool
+	clientSubnet       string
+	dnssecValidate     bool
+	trustAnchorFile    string
+	trace              bool
+	rootHintsFile      string
+	nameserverPort     string
+	resolverPort       string
+	authoritative      bool
+	zoneHealth         bool
+	delegation         bool
+	pslFile            string
+	zoneCut            string
+	followCNAME        bool
+	probeENT           bool
+	maxCNAMEDepth      string
+	takeoverAudit      bool
+	takeoverSignatures string
+	legacyRecords      bool
+	tsigKey            string
+	tsigFile           string
+	zoneFileDir        string
+	update             bool
+	queryAll           bool
+	showHelp           bool
+}
+
+func parseArgs(args []string) cliOptions {
+	var opts cliOptions
+
+	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
-		switch {
-		case arg == "--help" || arg == "-h":
-			showHelp = true
-			i++
+		// Long flags accept both "--flag value" and "--flag=value"
+		name, inlineValue, hasInlineValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			name, inlineValue, hasInlineValue = strings.Cut(arg, "=")
+		}
 
-		case arg == "--dns":
-			if i+1 < len(args) {
-				i++
-				dnsArg = args[i]
-			} else {
-				fmt.Println("Error: --dns requires a value")
+		value := func() string {
+			if hasInlineValue {
+				return inlineValue
+			}
+			if i+1 >= len(args) {
+				fmt.Printf("Error: %s requires a value\n", name)
 				os.Exit(1)
 			}
 			i++
+			return args[i]
+		}
 
-		case strings.HasPrefix(arg, "--dns="):
-			dnsArg = strings.TrimPrefix(arg, "--dns=")
-			i++
+		switch name {
+		case "--help", "-h":
+			opts.showHelp = true
 
-		case arg == "--output" || arg == "-o":
-			if i+1 < len(args) {
-				i++
-				outputFile = args[i]
-			} else {
-				fmt.Println("Error: --output requires a value")
-				os.Exit(1)
-			}
-			i++
+		case "--dns":
+			opts.dnsArg = value()
 
-		case strings.HasPrefix(arg, "--output="):
-			outputFile = strings.TrimPrefix(arg, "--output=")
-			i++
+		case "--output", "-o":
+			opts.outputFile = value()
 
-		case arg == "--format" || arg == "-f":
-			if i+1 < len(args) {
-				i++
-				formatArg = args[i]
-			} else {
-				fmt.Println("Error: --format requires a value")
-				os.Exit(1)
-			}
-			i++
+		case "--format", "-f":
+			opts.formatArg = value()
 
-		case strings.HasPrefix(arg, "--format="):
-			formatArg = strings.TrimPrefix(arg, "--format=")
-			i++
+		case "--legacy-records":
+			opts.legacyRecords = true
 
-		case arg == "--timeout" || arg == "-t":
-			if i+1 < len(args) {
-				i++
-				timeoutArg = args[i]
-			} else {
-				fmt.Println("Error: --timeout requires a value")
-				os.Exit(1)
-			}
-			i++
+		case "--timeout", "-t":
+			opts.timeoutArg = value()
 
-		case strings.HasPrefix(arg, "--timeout="):
-			timeoutArg = strings.TrimPrefix(arg, "--timeout=")
-			i++
+		case "--retry", "-r":
+			opts.retryArg = value()
 
-		case arg == "--retry" || arg == "-r":
-			if i+1 < len(args) {
-				i++
-				retryArg = args[i]
-			} else {
-				fmt.Println("Error: --retry requires a value")
+		case "--workers", "-w":
+			opts.workersArg = value()
+
+		case "--transport":
+			opts.transportOverride = strings.ToLower(value())
+			if _, err := query.ParseTransport(opts.transportOverride); err != nil {
+				fmt.Printf("Error: --transport must be 'udp', 'tcp', 'dot', 'doh' or 'doq', got '%s'\n", opts.transportOverride)
 				os.Exit(1)
 			}
-			i++
 
-		case strings.HasPrefix(arg, "--retry="):
-			retryArg = strings.TrimPrefix(arg, "--retry=")
-			i++
+		case "--tls-name":
+			opts.tlsServerName = value()
 
-		case arg == "--workers" || arg == "-w":
-			if i+1 < len(args) {
-				i++
-				workersArg = args[i]
-			} else {
-				fmt.Println("Error: --workers requires a value")
-				os.Exit(1)
-			}
-			i++
+		case "--tls-pin":
+			opts.tlsPin = value()
 
-		case strings.HasPrefix(arg, "--workers="):
-			workersArg = strings.TrimPrefix(arg, "--workers=")
-			i++
+		case "--resolver-port":
+			opts.resolverPort = value()
 
-		case arg == "--transport":
-			if i+1 < len(args) {
-				i++
-				transportOverride = strings.ToLower(args[i])
-				if transportOverride != "tcp" && transportOverride != "udp" {
-					fmt.Printf("Error: --transport must be 'tcp' or 'udp', got '%s'\n", transportOverride)
-					os.Exit(1)
-				}
-			} else {
-				fmt.Println("Error: --transport requires a value (tcp or udp)")
-				os.Exit(1)
-			}
-			i++
+		case "--doh-method":
+			opts.dohMethod = value()
 
-		case strings.HasPrefix(arg, "--transport="):
-			transportOverride = strings.ToLower(strings.TrimPrefix(arg, "--transport="))
-			if transportOverride != "tcp" && transportOverride != "udp" {
-				fmt.Printf("Error: --transport must be 'tcp' or 'udp', got '%s'\n", transportOverride)
-				os.Exit(1)
-			}
-			i++
+		case "--no-tcp-fallback":
+			opts.noTCPFallback = true
 
-		case arg == "--query-all":
-			queryAll = true
-			i++
+		case "--edns-bufsize":
+			opts.ednsBufSize = value()
 
-		case strings.HasPrefix(arg, "-"):
-			fmt.Printf("Error: unknown flag '%s'\n", arg)
-			fmt.Println("Run 'dns_query_utility --help' for usage")
-			os.Exit(1)
+		case "--edns-do":
+			opts.ednsDO = true
+
+		case "--no-edns":
+			opts.noEDNS = true
+
+		case "--ecs":
+			opts.clientSubnet = value()
+
+		case "--dnssec-validate":
+			opts.dnssecValidate = true
+
+		case "--trust-anchor":
+			opts.trustAnchorFile = value()
+
+		case "--trace":
+			opts.trace = true
+
+		case "--root-hints":
+			opts.rootHintsFile = value()
+
+		case "--nameserver-port":
+			opts.nameserverPort = value()
+
+		case "--authoritative":
+			opts.authoritative = true
+
+		case "--zone-health":
+			opts.zoneHealth = true
+
+		case "--delegation":
+			opts.delegation = true
+
+		case "--psl-file":
+			opts.pslFile = value()
+
+		case "--zone-cut":
+			opts.zoneCut = value()
+
+		case "--follow-cname":
+			opts.followCNAME = true
+
+		case "--probe-ent":
+			opts.probeENT = true
+
+		case "--max-cname-depth":
+			opts.maxCNAMEDepth = value()
+
+		case "--takeover-audit":
+			opts.takeoverAudit = true
+
+		case "--takeover-signatures":
+			opts.takeoverSignatures = value()
+
+		case "--tsig":
+			opts.tsigKey = value()
+
+		case "--tsig-file":
+			opts.tsigFile = value()
+
+		case "--zone-dir":
+			opts.zoneFileDir = value()
+
+		case "--update":
+			opts.update = true
+
+		case "--query-all":
+			opts.queryAll = true
 
 		default:
-			if csvFile == "" {
-				csvFile = arg
-			} else {
+			if strings.HasPrefix(arg, "-") {
+				fmt.Printf("Error: unknown flag '%s'\n", arg)
+				fmt.Println("Run 'dns_query_utility --help' for usage")
+				os.Exit(1)
+			}
+			if opts.csvFile != "" {
 				fmt.Printf("Error: unexpected argument '%s'\n", arg)
 				os.Exit(1)
 			}
-			i++
+			opts.csvFile = arg
 		}
 	}
 
-	return csvFile, dnsArg, outputFile, formatArg, timeoutArg, retryArg, workersArg, transportOverride, queryAll, showHelp
+	return opts
 }
 
 func printUsage() {
@@ -633,10 +1115,23 @@ INPUT CSV FORMAT:
 
   Columns:
     domain      - Domain name to query (e.g., google.com)
-    query_type  - DNS record type: A, AAAA, MX, TXT, NS, SOA, CNAME, PTR, SRV, ANY
-    transport   - Protocol: udp or tcp
+    query_type  - DNS record type: A, AAAA, MX, TXT, NS, SOA, CNAME, PTR, SRV,
+                  CAA, DNSKEY, DS, HTTPS, SVCB, TLSA, NAPTR, SSHFP, ANY,
+                  or TYPEnnn for any other type code (e.g., TYPE99).
+                  Rows with an unknown type are skipped.
+    transport   - Protocol: udp, tcp, dot (DNS-over-TLS), doh (DNS-over-HTTPS)
+                  or doq (DNS-over-QUIC)
     network     - IP version: ipv4 or ipv6
 
+  Optional columns (matched by header name, empty cells use the global flags):
+    edns          - on or off
+    edns_bufsize  - Advertised UDP buffer size (512-65535)
+    edns_do       - true or false (DNSSEC OK bit)
+    ecs           - Client subnet, e.g. 198.51.100.0/24, or "off"
+    serial        - SOA serial the client already has (IXFR rows)
+    tsig          - TSIG key name from --tsig-file, or "off"
+    class         - IN (default), CH (e.g. version.bind, id.server) or HS
+
   Example CSV:
     domain,query_type,transport,network
     google.com,A,udp,ipv4
@@ -647,6 +1142,29 @@ DNS OPTIONS:
   --dns <server>
       DNS server(s) to use for queries.
       Default: 8.8.8.8:53 (IPv4) and 2001:4860:4860::8888:53 (IPv6)
+      DoT and DoQ queries use port 853 unless a custom port is given.
+      An https:// URL may be added for DoH queries, e.g.
+      --dns "8.8.8.8 https://dns.google/dns-query{?dns}"
+
+  --tls-name <name>
+      TLS server name (SNI) used to verify DoT and DoQ servers.
+      Give one name for both servers or "ipv4-name ipv6-name".
+      Default: the server IP address
+
+  --tls-pin <base64>
+      SPKI pin (base64 SHA-256 of the public key) for DoT and DoQ servers.
+      When set, the pin replaces CA verification, so self-signed
+      certificates are accepted if the key matches.
+      The pin is also applied to the DoH server.
+
+  --resolver-port <port>
+      Plain DNS port of the --dns servers, used for follow-up lookups
+      (SOA, NS, CNAME chains, ENT probes, takeover audits).
+      Default: the --dns port for udp/tcp queries, 53 for dot, doh and doq
+
+  --doh-method <post|get>
+      HTTP method for DoH queries.
+      Default: post
 
   -t, --timeout <duration>
       Maximum time to wait for each DNS query response.
@@ -656,6 +1174,155 @@ DNS OPTIONS:
       Number of times to retry a failed query.
       Range: 0-10, Default: 2
 
+  --edns-bufsize <bytes>
+      EDNS0 UDP buffer size advertised in queries.
+      Range: 512-65535, Default: 1232
+
+  --edns-do
+      Set the DNSSEC OK (DO) bit so servers include DNSSEC records.
+
+  --no-edns
+      Send queries without an OPT record (legacy 512-byte behavior).
+
+  --ecs <subnet>
+      Send an EDNS Client Subnet option (RFC 7871) with every query,
+      e.g. --ecs 198.51.100.0/24 or --ecs 2001:db8::/56.
+      The scope prefix returned by the server is recorded per result.
+
+  --no-tcp-fallback
+      Keep truncated UDP responses instead of retrying them over TCP.
+      By default a response with the TC bit set is re-queried over TCP.
+
+DNSSEC OPTIONS:
+  --dnssec-validate
+      Validate every answer through the chain of trust (RRSIG, DNSKEY
+      and DS records fetched via the DNS server) and report secure,
+      insecure, bogus or indeterminate with the reason.
+
+  --trust-anchor <file>
+      DS or DNSKEY records (zone file format) to use as trust anchors.
+      Default: the root zone KSKs (KSK-2017 and KSK-2024)
+
+DELEGATION OPTIONS:
+  --trace
+      Resolve every query iteratively from the root servers (like
+      dig +trace) instead of asking the DNS server. Each server queried,
+      its latency and the referral it returned are recorded in the result.
+      Trace queries use plain UDP/TCP on port 53.
+
+  --root-hints <file>
+      Root server NS/A/AAAA records in named.root format.
+      Default: built-in root hints
+
+  --nameserver-port <port>
+      Port of the root and delegated nameservers that are queried
+      directly by --trace, --authoritative, --zone-health and
+      --delegation. Only meant for test hierarchies on a local machine.
+      Default: 53
+
+  --authoritative
+      Also send every query with RD=0 to each IPv4 and IPv6 address of
+      the domain's authoritative nameservers and compare their answers.
+      Reports each server's answer, AA flag and latency, and whether
+      all servers agree.
+
+  --zone-health
+      Query the SOA of each domain's zone from every authoritative
+      server and flag serial, MNAME or RNAME mismatches and unreachable
+      servers. Each result gets a zone_health section, and the JSON
+      output lists every zone checked in a top-level zone_health section.
+
+  --delegation
+      Compare the NS set in the parent zone's referral with the NS set
+      served by the zone itself, check in-bailiwick glue against the
+      zone's A/AAAA records and report lame servers. Each result
+      gets a delegation section.
+
+  --zone-cut <psl|soa>
+      How the zone is found when looking up authoritative_ns:
+        psl  Registrable domain from the Public Suffix List (default)
+        soa  Walk SOA queries up from the domain to the zone apex
+
+  --psl-file <file>
+      Public Suffix List in public_suffix_list.dat format.
+      Default: embedded copy of the list
+
+CNAME OPTIONS:
+  Answers that are aliases record their CNAME chain in order, with
+  the TTL of each hop and the server that returned it.
+
+  --follow-cname
+      When the server returns a chain without records for its last
+      name, query the resolver (or trace from the root in --trace
+      mode) for the rest of the chain.
+
+  --max-cname-depth <hops>
+      Report chains with more hops than this as too long and stop
+      following them. Loops are always reported.
+      Range: 1-32, Default: 8
+
+NEGATIVE ANSWER OPTIONS:
+  --probe-ent
+      When a NODATA response has no NSEC records, query the name for
+      type ANY to tell an empty non-terminal from a name with other
+      records. Costs one extra query per NODATA answer.
+
+TAKEOVER AUDIT OPTIONS:
+  --takeover-audit
+      Audit the CNAME chains found by the queries for dangling aliases
+      that could be taken over: targets that return NXDOMAIN, and
+      targets at cloud providers where an unclaimed resource can be
+      registered by anyone. Findings are rated high, medium or low;
+      targets that return SERVFAIL are reported as indeterminate.
+      They are written to a takeover_report section of the JSON
+      output. Run it only against domains you own.
+
+  --takeover-signatures <file>
+      Provider signatures, one "pattern condition provider" line each
+      (condition: nxdomain or always).
+      Default: built-in list of common cloud and hosting providers
+
+ZONE TRANSFER OPTIONS:
+  Use query type AXFR or IXFR in the CSV. Transfers run over TCP
+  (udp rows are sent over TCP) or TLS for dot rows. For IXFR, add a
+  'serial' column with the SOA serial you already have.
+
+  --zone-dir <directory>
+      Also write each transferred zone to
+      <directory>/<zone>-<axfr|ixfr>-<network>.zone
+
+TSIG OPTIONS:
+  Signed queries record whether the response signature verified. A
+  'tsig' CSV column selects a key per row by name, or 'off'.
+
+  --tsig <[algorithm:]name:secret | name>
+      Sign every query and transfer with a TSIG key (base64 secret,
+      like dig -y), or with a key from --tsig-file by name.
+      Algorithms: hmac-sha1, hmac-sha224, hmac-sha256 (default),
+      hmac-sha384, hmac-sha512
+
+  --tsig-file <file>
+      BIND-style key file with one or more key { algorithm; secret; }
+      statements (e.g. output of tsig-keygen)
+
+DYNAMIC UPDATE OPTIONS:
+  --update
+      Treat the CSV as RFC 2136 UPDATE operations instead of queries:
+
+        action,record,transport,network,zone
+        add,www.example.com. 300 IN A 192.0.2.10,udp,ipv4,
+        delete,old.example.com. A,udp,ipv4,
+        replace,mail.example.com. 300 IN MX 10 mx1.example.com.,tcp,ipv4,example.com
+
+      action  - add, delete or replace (replaces the whole RRset)
+      record  - Record in zone file format. For delete, "name TYPE"
+                removes the RRset and "name ANY" every record at the name
+      zone    - Optional zone to update; found by SOA queries if empty
+      tsig    - Optional TSIG key name from --tsig-file, or "off"
+
+      Each update is verified with a follow-up query to the same server.
+      Operations run concurrently; use --workers 1 to apply them in order.
+
 PERFORMANCE OPTIONS:
   -w, --workers <count>
       Number of concurrent workers (manual override).
@@ -667,22 +1334,26 @@ PERFORMANCE OPTIONS:
         --workers 100    Use 100 workers for large batches
 
 OVERRIDE OPTIONS:
-  --transport <tcp|udp>
+  --transport <udp|tcp|dot|doh|doq>
       Override transport protocol for ALL queries.
       Ignores 'transport' column in CSV.
 
       Examples:
         --transport tcp   Force all queries to use TCP
         --transport udp   Force all queries to use UDP
+        --transport dot   Force all queries to use DNS-over-TLS
+        --transport doh   Force all queries to use DNS-over-HTTPS
+        --transport doq   Force all queries to use DNS-over-QUIC
 
   --query-all
       Query ALL record types for each domain.
-      Expands each domain to: A, AAAA, MX, TXT, NS, SOA, CNAME, PTR, SRV
+      Expands each domain to: A, AAAA, MX, TXT, NS, SOA, CNAME, PTR, SRV,
+      CAA, DNSKEY, DS, HTTPS, SVCB, TLSA, NAPTR, SSHFP
       Ignores 'query_type' column in CSV.
       
       NOTE: Does NOT include ANY queries (redundant with individual types)
       
-      Example: If CSV has 10 domains, this generates 90 queries (10×9 types)
+      Example: If CSV has 10 domains, this generates 170 queries (10×17 types)
 
 OUTPUT OPTIONS:
   -o, --output <filename>
@@ -693,6 +1364,11 @@ OUTPUT OPTIONS:
       Output file format: json, csv, all
       Default: json
 
+  --legacy-records
+      Also write the legacy "records" strings (e.g. "MX:10 mx.example.com.")
+      to the JSON output. Typed records are always written to "answers".
+      The CSV output always has a records column.
+
 OTHER:
   -h, --help
       Show this help message.
@@ -711,6 +1387,14 @@ EXAMPLES:
   Query all record types:
     $ dns_query_utility queries.csv --query-all
 
+  Compare a DoH endpoint with a plain resolver:
+    $ dns_query_utility queries.csv \
+        --dns "8.8.8.8 https://cloudflare-dns.com/dns-query"
+
+  Audit a DNS-over-TLS resolver:
+    $ dns_query_utility queries.csv --dns 1.1.1.1 \
+        --transport dot --tls-name cloudflare-dns.com
+
   Combined overrides:
     $ dns_query_utility queries.csv \
         --dns 1.1.1.1 \
@@ -10,7 +10,10 @@ import (
 
 // ConsolidatedJSONWriter writes consolidated results to JSON format
 type ConsolidatedJSONWriter struct {
-    filepath string
+    filepath       string
+    LegacyRecords  bool                   // Keep the legacy "records" strings next to "answers"
+    TakeoverReport *result.TakeoverReport // Written as a takeover_report section when set
+    ZoneHealth     []result.ZoneHealth    // Written as a zone_health section when set
 }
 
 // NewConsolidatedJSONWriter creates a new consolidated JSON writer
@@ -20,15 +23,28 @@ func NewConsolidatedJSONWriter(filepath string) *ConsolidatedJSONWriter {
 
 // ConsolidatedJSONOutput represents the consolidated JSON output structure
 type ConsolidatedJSONOutput struct {
-    Metadata Metadata                      `json:"metadata"`
-    Results  []result.ConsolidatedResult   `json:"results"`
+    Metadata       Metadata                    `json:"metadata"`
+    Results        []result.ConsolidatedResult `json:"results"`
+    TakeoverReport *result.TakeoverReport      `json:"takeover_report,omitempty"`
+    ZoneHealth     []result.ZoneHealth         `json:"zone_health,omitempty"`
 }
 
 // WriteConsolidated outputs consolidated results to JSON file
 func (w *ConsolidatedJSONWriter) WriteConsolidated(results []result.ConsolidatedResult, metadata Metadata) error {
+    if !w.LegacyRecords {
+        for _, cr := range results {
+            for key, typeRes := range cr.QueryTypes {
+                typeRes.Records = nil
+                cr.QueryTypes[key] = typeRes
+            }
+        }
+    }
+
     output := ConsolidatedJSONOutput{
-        Metadata: metadata,
-        Results:  results,
+        Metadata:       metadata,
+        Results:        results,
+        TakeoverReport: w.TakeoverReport,
+        ZoneHealth:     w.ZoneHealth,
     }
 
     // Create file
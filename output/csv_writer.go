@@ -42,6 +42,14 @@ func (w *CSVWriter) Write(results []result.QueryResult, metadata Metadata) error
 		"resolved_ips",
 		"records",
 		"authoritative_ns",
+		"message_id",
+		"flags",
+		"response_size",
+		"min_ttl",
+		"ttls",
+		"cname_chain",
+		"negative_kind",
+		"negative_ttl",
 		"error",
 		"timestamp",
 	}
@@ -62,6 +70,14 @@ func (w *CSVWriter) Write(results []result.QueryResult, metadata Metadata) error
 			joinIPs(res.ResolvedIPs),
 			joinRecords(res.Records),
 			joinRecords(res.AuthoritativeNS),
+			messageID(res.Header),
+			flags(res.Header),
+			responseSize(res.ResponseSize),
+			minTTL(res.MinTTL),
+			joinTTLs(res.TTLs),
+			cnameChain(res.CNAMEChain),
+			negativeKind(res.Negative),
+			negativeTTL(res.Negative),
 			res.Error,
 			res.Timestamp.Format("2006-01-02 15:04:05.000"),
 		}
@@ -88,3 +104,74 @@ func joinRecords(records []string) string {
 	}
 	return strings.Join(records, "; ")
 }
+
+// messageID formats the response message ID, empty when there was no response
+func messageID(header *result.HeaderInfo) string {
+	if header == nil {
+		return ""
+	}
+	return strconv.Itoa(int(header.ID))
+}
+
+// flags formats the response header flags dig style, e.g. "rd ra ad"
+func flags(header *result.HeaderInfo) string {
+	if header == nil {
+		return ""
+	}
+	return header.Flags()
+}
+
+// responseSize formats the response size, empty when there was no response
+func responseSize(size int) string {
+	if size == 0 {
+		return ""
+	}
+	return strconv.Itoa(size)
+}
+
+// minTTL formats the lowest answer TTL, empty when there were no answers
+func minTTL(ttl *uint32) string {
+	if ttl == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*ttl), 10)
+}
+
+// joinTTLs converts answer TTLs to semicolon-separated "name TYPE ttl" entries
+func joinTTLs(ttls []result.RecordTTL) string {
+	entries := make([]string, 0, len(ttls))
+	for _, ttl := range ttls {
+		entries = append(entries, fmt.Sprintf("%s %s %d", ttl.Name, ttl.Type, ttl.TTL))
+	}
+	return strings.Join(entries, "; ")
+}
+
+// cnameChain formats a CNAME chain as "name -> target -> ...", empty when the
+// answer was not an alias
+func cnameChain(chain *result.CNAMEChain) string {
+	if chain == nil {
+		return ""
+	}
+	names := []string{chain.Hops[0].Name}
+	for _, hop := range chain.Hops {
+		names = append(names, hop.Target)
+	}
+	return strings.Join(names, " -> ")
+}
+
+// negativeKind formats the kind of negative answer, empty for answers
+func negativeKind(neg *result.NegativeResult) string {
+	if neg == nil {
+		return ""
+	}
+	return string(neg.Kind)
+}
+
+// negativeTTL formats how long a negative answer may be cached, empty when
+// there was no SOA to take it from
+func negativeTTL(neg *result.NegativeResult) string {
+	if neg == nil {
+		return ""
+	}
+	return minTTL(neg.NegativeTTL)
+}
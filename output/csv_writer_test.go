@@ -0,0 +1,81 @@
+package output
+
+import (
+	"dns_query_utility/result"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCSVWriterHeaderAndTTLColumns(t *testing.T) {
+	minTTL := uint32(60)
+	results := []result.QueryResult{
+		{
+			Domain:       "www.example.com",
+			QueryType:    "A",
+			Status:       result.StatusSuccess,
+			Header:       &result.HeaderInfo{ID: 4242, RD: true, RA: true, AD: true},
+			ResponseSize: 96,
+			MinTTL:       &minTTL,
+			TTLs: []result.RecordTTL{
+				{Name: "www.example.com.", Type: "CNAME", TTL: 300},
+				{Name: "web.example.com.", Type: "A", TTL: 60},
+			},
+			Timestamp: time.Now(),
+		},
+		{
+			Domain:    "down.example.com",
+			QueryType: "A",
+			Status:    result.StatusTimeout,
+			Error:     "i/o timeout",
+			Timestamp: time.Now(),
+		},
+	}
+
+	tests := []struct {
+		column string
+		want   []string // Value in each row
+	}{
+		{"message_id", []string{"4242", ""}},
+		{"flags", []string{"rd ra ad", ""}},
+		{"response_size", []string{"96", ""}},
+		{"min_ttl", []string{"60", ""}},
+		{"ttls", []string{"www.example.com. CNAME 300; web.example.com. A 60", ""}},
+	}
+
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := NewCSVWriter(path).Write(results, Metadata{}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	if len(rows) != len(results)+1 {
+		t.Fatalf("%d rows, want a header and %d results", len(rows), len(results))
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, tt := range tests {
+		i, ok := columns[tt.column]
+		if !ok {
+			t.Errorf("no %s column in %v", tt.column, rows[0])
+			continue
+		}
+		for row, want := range tt.want {
+			if got := rows[row+1][i]; got != want {
+				t.Errorf("%s of %s = %q, want %q", tt.column, results[row].Domain, got, want)
+			}
+		}
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 // JSONWriter writes results to JSON format
 type JSONWriter struct {
-    filepath string
+    filepath       string
+    LegacyRecords  bool                   // Keep the legacy "records" strings next to "answers"
+    TakeoverReport *result.TakeoverReport // Written as a takeover_report section when set
+    ZoneHealth     []result.ZoneHealth    // Written as a zone_health section when set
 }
 
 // NewJSONWriter creates a new JSON writer
@@ -20,8 +23,10 @@ func NewJSONWriter(filepath string) *JSONWriter {
 
 // JSONOutput represents the complete JSON output structure
 type JSONOutput struct {
-    Metadata Metadata              `json:"metadata"`
-    Results  []result.QueryResult  `json:"results"`
+    Metadata       Metadata               `json:"metadata"`
+    Results        []result.QueryResult   `json:"results"`
+    TakeoverReport *result.TakeoverReport `json:"takeover_report,omitempty"`
+    ZoneHealth     []result.ZoneHealth    `json:"zone_health,omitempty"`
 }
 
 // Write outputs results to JSON file
@@ -33,9 +38,19 @@ func (w *JSONWriter) Write(results []result.QueryResult, metadata Metadata) erro
         }
     }
 
+    // Drop the legacy strings from a copy, since the caller still displays them
+    if !w.LegacyRecords {
+        results = append([]result.QueryResult(nil), results...)
+        for i := range results {
+            results[i].Records = nil
+        }
+    }
+
     output := JSONOutput{
-        Metadata: metadata,
-        Results:  results,
+        Metadata:       metadata,
+        Results:        results,
+        TakeoverReport: w.TakeoverReport,
+        ZoneHealth:     w.ZoneHealth,
     }
 
     // Create file
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"dns_query_utility/result"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteOutputLegacyRecords(t *testing.T) {
+	tests := []struct {
+		name          string
+		consolidate   bool
+		legacyRecords bool
+	}{
+		{name: "plain", consolidate: false, legacyRecords: false},
+		{name: "plain with legacy records", consolidate: false, legacyRecords: true},
+		{name: "consolidated", consolidate: true, legacyRecords: false},
+		{name: "consolidated with legacy records", consolidate: true, legacyRecords: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []result.QueryResult{{
+				Domain:    "example.com",
+				QueryType: "MX",
+				Transport: "udp",
+				IPVersion: "ipv4",
+				Status:    result.StatusSuccess,
+				Records:   []string{"MX:10 mx.example.com."},
+				Answers: []result.Record{{
+					Name: "example.com.", Type: "MX", Class: "IN", TTL: 300, Data: "10 mx.example.com.",
+					MX: &result.MXData{Preference: 10, Exchange: "mx.example.com."},
+				}},
+			}}
+
+			path := filepath.Join(t.TempDir(), "results.json")
+			if err := WriteOutput(path, FormatJSON, results, Metadata{}, tt.consolidate, tt.legacyRecords, nil); err != nil {
+				t.Fatalf("WriteOutput: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+
+			// Only the fields under test are decoded
+			var typeResults []map[string]json.RawMessage
+			if tt.consolidate {
+				var output struct {
+					Results []struct {
+						QueryTypes map[string]map[string]json.RawMessage `json:"query_types"`
+					} `json:"results"`
+				}
+				if err := json.Unmarshal(data, &output); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				for _, cr := range output.Results {
+					for _, typeResult := range cr.QueryTypes {
+						typeResults = append(typeResults, typeResult)
+					}
+				}
+			} else {
+				var output struct {
+					Results []map[string]json.RawMessage `json:"results"`
+				}
+				if err := json.Unmarshal(data, &output); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				typeResults = output.Results
+			}
+
+			if len(typeResults) != 1 {
+				t.Fatalf("%d results in %s", len(typeResults), data)
+			}
+			if _, ok := typeResults[0]["answers"]; !ok {
+				t.Errorf("no answers in %s", data)
+			}
+			raw, ok := typeResults[0]["records"]
+			var records []string
+			if ok {
+				if err := json.Unmarshal(raw, &records); err != nil {
+					t.Fatalf("decode records: %v", err)
+				}
+			}
+			switch {
+			case tt.legacyRecords && (len(records) != 1 || records[0] != "MX:10 mx.example.com."):
+				t.Errorf("records = %q, want the legacy strings", records)
+			case !tt.legacyRecords && ok:
+				t.Errorf("records = %q, want none without --legacy-records", records)
+			}
+
+			// The caller still displays the legacy strings
+			if len(results[0].Records) != 1 {
+				t.Errorf("caller's records = %v after writing", results[0].Records)
+			}
+		})
+	}
+}
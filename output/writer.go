@@ -27,6 +27,7 @@ type Metadata struct {
 	QueriesPerSecond  float64   `json:"queries_per_second"`
 	DNSServerIPv4     string    `json:"dns_server_ipv4"`
 	DNSServerIPv6     string    `json:"dns_server_ipv6"`
+	DoHURL            string    `json:"doh_url,omitempty"`
 	WorkersUsed       int       `json:"workers_used"`
 	TimeoutSeconds    float64   `json:"timeout_seconds"`
 	RetryCount        int       `json:"retry_count"`
@@ -43,8 +44,11 @@ type ConsolidatedWriter interface {
 	WriteConsolidated(results []result.ConsolidatedResult, metadata Metadata) error
 }
 
-// WriteOutput writes results to file(s) based on format
-func WriteOutput(filepath string, format Format, results []result.QueryResult, metadata Metadata, consolidate bool) error {
+// WriteOutput writes results to file(s) based on format. The JSON output only
+// keeps the legacy "records" strings with legacyRecords, and a takeover
+// report, when given, is added to it, as are the zone health checks of
+// --zone-health runs.
+func WriteOutput(filepath string, format Format, results []result.QueryResult, metadata Metadata, consolidate bool, legacyRecords bool, takeover *result.TakeoverReport) error {
 	switch format {
 	case FormatCSV:
 		w := NewCSVWriter(filepath)
@@ -55,11 +59,17 @@ func WriteOutput(filepath string, format Format, results []result.QueryResult, m
 			// Use consolidated format
 			consolidated := result.ConsolidateResults(results)
 			w := NewConsolidatedJSONWriter(filepath)
+			w.LegacyRecords = legacyRecords
+			w.TakeoverReport = takeover
+			w.ZoneHealth = result.CollectZoneHealth(results)
 			metadata.ConsolidatedMode = true
 			return w.WriteConsolidated(consolidated, metadata)
 		}
 		// Normal JSON format
 		w := NewJSONWriter(filepath)
+		w.LegacyRecords = legacyRecords
+		w.TakeoverReport = takeover
+		w.ZoneHealth = result.CollectZoneHealth(results)
 		return w.Write(results, metadata)
 
 	case FormatAll:
@@ -75,11 +85,17 @@ func WriteOutput(filepath string, format Format, results []result.QueryResult, m
 		if consolidate {
 			consolidated := result.ConsolidateResults(results)
 			jsonWriter := NewConsolidatedJSONWriter(jsonPath)
+			jsonWriter.LegacyRecords = legacyRecords
+			jsonWriter.TakeoverReport = takeover
+			jsonWriter.ZoneHealth = result.CollectZoneHealth(results)
 			metadata.ConsolidatedMode = true
 			return jsonWriter.WriteConsolidated(consolidated, metadata)
 		}
 
 		jsonWriter := NewJSONWriter(jsonPath)
+		jsonWriter.LegacyRecords = legacyRecords
+		jsonWriter.TakeoverReport = takeover
+		jsonWriter.ZoneHealth = result.CollectZoneHealth(results)
 		return jsonWriter.Write(results, metadata)
 
 	default:
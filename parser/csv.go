@@ -1,10 +1,12 @@
 package parser
 
 import (
+	"dns_query_utility/config"
 	"dns_query_utility/query"
 	"encoding/csv"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -28,14 +30,26 @@ func ParseCSV(filepath string) ([]query.QuerySpec, error) {
 
 	specs := make([]query.QuerySpec, 0, len(rows)-1)
 
+	// Columns after the four required ones are optional and matched by header name
+	optional := make(map[string]int)
+	for idx := 4; idx < len(rows[0]); idx++ {
+		optional[strings.ToLower(strings.TrimSpace(rows[0][idx]))] = idx
+	}
+	column := func(row []string, name string) string {
+		if idx, ok := optional[name]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+
 	for i, row := range rows {
 		// Skip header row
 		if i == 0 {
 			continue
 		}
 
-		if len(row) != 4 {
-			fmt.Printf("Warning: Skipping row %d - expected 4 columns, got %d\n", i+1, len(row))
+		if len(row) < 4 {
+			fmt.Printf("Warning: Skipping row %d - expected at least 4 columns, got %d\n", i+1, len(row))
 			continue
 		}
 
@@ -44,8 +58,12 @@ func ParseCSV(filepath string) ([]query.QuerySpec, error) {
 		transportStr := strings.TrimSpace(row[2])
 		ipVersionStr := strings.TrimSpace(row[3])
 
-		// Parse query type (returns QueryType directly, no error)
-		queryType := query.ParseQueryType(queryTypeStr)
+		// Parse query type (named type or RFC 3597 "TYPEnnn")
+		queryType, err := query.ParseQueryType(queryTypeStr)
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
 
 		// Parse transport (UDP/TCP)
 		transport, err := query.ParseTransport(transportStr)
@@ -61,11 +79,53 @@ func ParseCSV(filepath string) ([]query.QuerySpec, error) {
 			continue
 		}
 
+		// Parse optional per-row EDNS settings
+		edns, err := parseEDNSColumns(column(row, "edns"), column(row, "edns_bufsize"), column(row, "edns_do"))
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
+
+		// Parse optional per-row client subnet ("off" disables the global one)
+		clientSubnet := column(row, "ecs")
+		if clientSubnet != "" && !strings.EqualFold(clientSubnet, "off") {
+			if err := config.ValidateClientSubnet(clientSubnet); err != nil {
+				fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+				continue
+			}
+		}
+
+		// Parse optional SOA serial for IXFR queries
+		var serial uint32
+		if value := column(row, "serial"); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				fmt.Printf("Warning: Skipping row %d - invalid serial '%s'\n", i+1, value)
+				continue
+			}
+			serial = uint32(parsed)
+		}
+
+		// Parse optional query class (IN when empty)
+		class, err := query.ParseQueryClass(column(row, "class"))
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
+
+		// Parse optional per-row TSIG key name ("off" disables the default key)
+		tsigKey := column(row, "tsig")
+
 		spec := query.QuerySpec{
-			Domain:    domain,
-			QueryType: queryType,
-			Transport: transport,
-			IPVersion: ipVersion,
+			Domain:       domain,
+			QueryType:    queryType,
+			Transport:    transport,
+			IPVersion:    ipVersion,
+			EDNS:         edns,
+			Class:        class,
+			ClientSubnet: clientSubnet,
+			Serial:       serial,
+			TSIGKey:      tsigKey,
 		}
 
 		if err := spec.Validate(); err != nil {
@@ -82,3 +142,47 @@ func ParseCSV(filepath string) ([]query.QuerySpec, error) {
 
 	return specs, nil
 }
+
+// parseEDNSColumns converts the optional edns, edns_bufsize and edns_do cells into
+// an override; empty cells inherit the global setting
+func parseEDNSColumns(enabled, bufsize, do string) (query.EDNSOverride, error) {
+	var override query.EDNSOverride
+
+	if enabled != "" {
+		value, ok := parseSwitch(enabled)
+		if !ok {
+			return override, fmt.Errorf("invalid edns value '%s': must be 'on' or 'off'", enabled)
+		}
+		override.Enabled = &value
+	}
+
+	if bufsize != "" {
+		size, err := strconv.ParseUint(bufsize, 10, 16)
+		if err != nil || size < config.MinEDNSBufSize {
+			return override, fmt.Errorf("invalid edns_bufsize '%s': must be %d-65535", bufsize, config.MinEDNSBufSize)
+		}
+		udpSize := uint16(size)
+		override.UDPSize = &udpSize
+	}
+
+	if do != "" {
+		value, ok := parseSwitch(do)
+		if !ok {
+			return override, fmt.Errorf("invalid edns_do value '%s': must be 'on' or 'off'", do)
+		}
+		override.DO = &value
+	}
+
+	return override, nil
+}
+
+// parseSwitch reads an on/off cell, also accepting true/false, yes/no and 1/0
+func parseSwitch(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		return true, true
+	case "off", "false", "no", "0":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"dns_query_utility/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseEDNSColumns(t *testing.T) {
+	base := config.DefaultEDNS()
+
+	tests := []struct {
+		name    string
+		enabled string
+		bufsize string
+		do      string
+		want    config.EDNSOptions
+		wantErr bool
+	}{
+		{name: "empty cells inherit", want: base},
+		{name: "on and yes", enabled: "on", do: "yes", want: config.EDNSOptions{Enabled: true, UDPSize: base.UDPSize, DO: true}},
+		{name: "true and 1", enabled: "TRUE", do: "1", want: config.EDNSOptions{Enabled: true, UDPSize: base.UDPSize, DO: true}},
+		{name: "off and no", enabled: "off", do: "no", want: config.EDNSOptions{UDPSize: base.UDPSize}},
+		{name: "buffer size", bufsize: "4096", do: "on", want: config.EDNSOptions{Enabled: true, UDPSize: 4096, DO: true}},
+		{name: "invalid edns", enabled: "maybe", wantErr: true},
+		{name: "invalid edns_do", do: "sometimes", wantErr: true},
+		{name: "buffer too small", bufsize: "511", wantErr: true},
+		{name: "buffer too large", bufsize: "65536", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			override, err := parseEDNSColumns(tt.enabled, tt.bufsize, tt.do)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := override.Resolve(base); got != tt.want {
+					t.Errorf("resolved %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCSVEDNSColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.csv")
+	data := "domain,query_type,transport,network,edns,edns_bufsize,edns_do\n" +
+		"example.com,DNSKEY,udp,ipv4,on,4096,yes\n" +
+		"example.com,A,udp,ipv4,off,,\n" +
+		"example.com,A,udp,ipv4,,,bogus\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, err := ParseCSV(path)
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d specs, want 2 (the invalid edns_do row skipped)", len(specs))
+	}
+
+	base := config.DefaultEDNS()
+	if got := specs[0].EDNS.Resolve(base); !got.Enabled || !got.DO || got.UDPSize != 4096 {
+		t.Errorf("row 2 EDNS = %+v, want enabled with DO and 4096 bytes", got)
+	}
+	if got := specs[1].EDNS.Resolve(base); got.Enabled {
+		t.Errorf("row 3 EDNS = %+v, want disabled", got)
+	}
+}
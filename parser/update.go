@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"dns_query_utility/query"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseUpdateCSV reads dynamic update operations for --update mode. Rows have
+// the columns action,record,transport,network followed by the optional zone
+// and tsig columns.
+func ParseUpdateCSV(filepath string) ([]query.QuerySpec, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+
+	specs := make([]query.QuerySpec, 0, len(rows)-1)
+
+	// Columns after the four required ones are optional and matched by header name
+	optional := make(map[string]int)
+	for idx := 4; idx < len(rows[0]); idx++ {
+		optional[strings.ToLower(strings.TrimSpace(rows[0][idx]))] = idx
+	}
+	column := func(row []string, name string) string {
+		if idx, ok := optional[name]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+
+	for i, row := range rows {
+		// Skip header row
+		if i == 0 {
+			continue
+		}
+
+		if len(row) < 4 {
+			fmt.Printf("Warning: Skipping row %d - expected at least 4 columns, got %d\n", i+1, len(row))
+			continue
+		}
+
+		update, err := query.ParseUpdate(strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), column(row, "zone"))
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
+
+		transport, err := query.ParseTransport(strings.TrimSpace(row[2]))
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
+
+		ipVersion, err := query.ParseIPVersion(strings.TrimSpace(row[3]))
+		if err != nil {
+			fmt.Printf("Warning: Skipping row %d - %v\n", i+1, err)
+			continue
+		}
+
+		spec := query.QuerySpec{
+			Domain:    strings.TrimSuffix(update.Name(), "."),
+			QueryType: update.Type(),
+			Transport: transport,
+			IPVersion: ipVersion,
+			TSIGKey:   column(row, "tsig"),
+			Update:    update,
+		}
+
+		if err := spec.Validate(); err != nil {
+			fmt.Printf("Warning: Skipping row %d - validation failed: %v\n", i+1, err)
+			continue
+		}
+
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no valid update operations found in CSV")
+	}
+
+	return specs, nil
+}
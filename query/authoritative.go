@@ -0,0 +1,245 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// zoneServers is the NS set of a zone with the addresses of each nameserver
+type zoneServers struct {
+	zone    string
+	servers []traceServer
+	err     error
+}
+
+// queryAuthoritative sends msg with RD=0 to every IPv4 and IPv6 address of
+// every authoritative nameserver of spec.Domain and compares their answers.
+// The NS set is looked up through the recursive resolver at resolver.
+func (s *Session) queryAuthoritative(msg *dns.Msg, spec QuerySpec, cfg config.Config, resolver string) *result.AuthoritativeResult {
+	zs := s.zoneServers(dns.Fqdn(spec.Domain), spec.IPVersion, cfg, resolver)
+
+	authResult := &result.AuthoritativeResult{
+		Zone:    zs.zone,
+		Servers: []result.AuthServerResult{},
+	}
+	if zs.err != nil {
+		authResult.Error = zs.err.Error()
+		return authResult
+	}
+
+	query := msg.Copy()
+	query.RecursionDesired = false
+
+	for _, server := range zs.servers {
+		authResult.Servers = append(authResult.Servers, queryAuthServer(query, server, spec, cfg))
+	}
+
+	authResult.Consistent, authResult.Reason = compareAuthAnswers(authResult.Servers)
+	return authResult
+}
+
+// queryAuthServer sends query to one authoritative server address
+func queryAuthServer(query *dns.Msg, server traceServer, spec QuerySpec, cfg config.Config) result.AuthServerResult {
+	serverResult := result.AuthServerResult{
+		Server:  server.name,
+		Address: server.address,
+	}
+
+	response, latency, err := exchangeAuthServer(query, server.address, spec.Transport, cfg)
+	serverResult.LatencyMs = latency
+	if err != nil {
+		serverResult.Error = err.Error()
+		return serverResult
+	}
+
+	serverResult.Rcode = dns.RcodeToString[response.Rcode]
+	serverResult.Authoritative = response.Authoritative
+
+	ips, records := parseAnswers(response.Answer)
+	serverResult.Answers = append(ips, records...)
+	sort.Strings(serverResult.Answers)
+
+	return serverResult
+}
+
+// exchangeAuthServer sends query to address over UDP (TCP for tcp rows and
+// truncated answers) and returns the response with its latency in milliseconds
+func exchangeAuthServer(query *dns.Msg, address string, transport Transport, cfg config.Config) (*dns.Msg, float64, error) {
+	ipv := IPv4
+	if host, _, err := net.SplitHostPort(address); err == nil && strings.Contains(host, ":") {
+		ipv = IPv6
+	}
+
+	if transport != TCP {
+		transport = UDP
+	}
+	client := &dns.Client{
+		Net:     transport.Network(ipv),
+		Timeout: cfg.Timeout,
+	}
+
+	startTime := time.Now()
+	response, _, err := client.Exchange(query, address)
+	if err == nil && response.Truncated && transport == UDP {
+		client.Net = TCP.Network(ipv)
+		response, _, err = client.Exchange(query, address)
+	}
+	return response, float64(time.Since(startTime).Nanoseconds()) / 1e6, err
+}
+
+// compareAuthAnswers reports whether all servers that responded returned the
+// same rcode and answer set with the AA flag, and if not, why
+func compareAuthAnswers(servers []result.AuthServerResult) (bool, string) {
+	var problems []string
+	answers := make(map[string][]string)
+	var order []string
+
+	for _, server := range servers {
+		if server.Error != "" {
+			continue
+		}
+		if !server.Authoritative {
+			problems = append(problems, fmt.Sprintf("%s (%s) did not answer authoritatively", server.Server, server.Address))
+		}
+
+		key := server.Rcode + " " + strings.Join(server.Answers, ", ")
+		if _, ok := answers[key]; !ok {
+			order = append(order, key)
+		}
+		answers[key] = append(answers[key], server.Address)
+	}
+
+	if len(order) == 0 {
+		return false, "no authoritative server responded"
+	}
+	if len(order) > 1 {
+		var groups []string
+		for _, key := range order {
+			groups = append(groups, fmt.Sprintf("[%s] from %s", key, strings.Join(answers[key], ", ")))
+		}
+		problems = append(problems, fmt.Sprintf("%d different answers: %s", len(order), strings.Join(groups, "; ")))
+	}
+
+	if len(problems) > 0 {
+		return false, strings.Join(problems, "; ")
+	}
+	return true, ""
+}
+
+// zoneServers finds the zone containing name and the addresses of its
+// nameservers. Results are cached in the session per name.
+func (s *Session) zoneServers(name string, ipv IPVersion, cfg config.Config, resolver string) *zoneServers {
+	key := resolver + "|" + dns.CanonicalName(name)
+	if zs, ok := s.authZones[key]; ok {
+		return zs
+	}
+
+	zs := lookupZoneServers(dns.CanonicalName(name), ipv, cfg, resolver)
+	s.authZones[key] = zs
+	return zs
+}
+
+// lookupZoneServers walks up from name until the resolver returns an NS set
+// for it, then resolves both the A and AAAA records of each nameserver
+func lookupZoneServers(name string, ipv IPVersion, cfg config.Config, resolver string) *zoneServers {
+	zs := &zoneServers{}
+	var nsNames []string
+	for zone := name; ; {
+		resp, err := resolverLookup(zone, dns.TypeNS, ipv, cfg, resolver)
+		if err != nil {
+			zs.err = fmt.Errorf("NS lookup for %s failed: %w", zone, err)
+			return zs
+		}
+		for _, rr := range resp.Answer {
+			if ns, ok := rr.(*dns.NS); ok && dns.CanonicalName(ns.Hdr.Name) == zone {
+				nsNames = append(nsNames, dns.CanonicalName(ns.Ns))
+			}
+		}
+		if len(nsNames) > 0 {
+			zs.zone = zone
+			break
+		}
+		if zone == "." {
+			zs.err = fmt.Errorf("no NS records found for %s or any parent zone", name)
+			return zs
+		}
+		zone = parentZone(zone)
+	}
+	sort.Strings(nsNames)
+
+	for _, nsName := range nsNames {
+		for _, address := range resolveAddresses(nsName, ipv, cfg, resolver) {
+			zs.servers = append(zs.servers, traceServer{name: nsName, address: address})
+		}
+	}
+	if len(zs.servers) == 0 {
+		zs.err = fmt.Errorf("no addresses found for the nameservers of %s", zs.zone)
+	}
+	return zs
+}
+
+// resolveAddresses looks up both the A and AAAA records of name through the
+// resolver and returns them as host:port strings on the nameserver port
+func resolveAddresses(name string, ipv IPVersion, cfg config.Config, resolver string) []string {
+	port := nameserverPort(cfg)
+
+	var addresses []string
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, err := resolverLookup(name, qtype, ipv, cfg, resolver)
+		if err != nil {
+			continue
+		}
+		for _, ip := range addressRecords(resp.Answer) {
+			addresses = append(addresses, net.JoinHostPort(ip, port))
+		}
+	}
+	return addresses
+}
+
+// resolverLookup sends a recursive query to the resolver, repeating a
+// truncated answer over TCP
+func resolverLookup(qname string, qtype uint16, ipv IPVersion, cfg config.Config, resolver string) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(qname, qtype)
+	msg.RecursionDesired = true
+
+	client := &dns.Client{
+		Net:     UDP.Network(ipv),
+		Timeout: cfg.Timeout,
+	}
+	resp, _, err := client.Exchange(msg, resolver)
+	if err == nil && resp.Truncated {
+		client.Net = TCP.Network(ipv)
+		resp, _, err = client.Exchange(msg, resolver)
+	}
+	return resp, err
+}
+
+// addressRecords returns the addresses of the A and AAAA records in rrs
+func addressRecords(rrs []dns.RR) []string {
+	var ips []string
+	for _, rr := range rrs {
+		switch record := rr.(type) {
+		case *dns.A:
+			ips = append(ips, record.A.String())
+		case *dns.AAAA:
+			ips = append(ips, record.AAAA.String())
+		}
+	}
+	return ips
+}
+
+// parentZone strips the leftmost label from zone ("example.com." -> "com.")
+func parentZone(zone string) string {
+	if next, end := dns.NextLabel(zone, 0); !end {
+		return zone[next:]
+	}
+	return "."
+}
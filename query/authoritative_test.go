@@ -0,0 +1,72 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// authServer answers like both the resolver and the only nameserver of example.
+func authServer(t *testing.T) int {
+	return startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Qtype == dns.TypeNS && q.Name == "example.":
+			m.Answer = []dns.RR{mustRR(t, "example. 3600 IN NS ns.example.")}
+		case q.Qtype == dns.TypeA && q.Name == "ns.example.":
+			m.Answer = []dns.RR{mustRR(t, "ns.example. 3600 IN A 127.0.0.1")}
+		case q.Qtype == dns.TypeA && q.Name == "www.example.":
+			m.Authoritative = !r.RecursionDesired
+			m.Answer = []dns.RR{mustRR(t, "www.example. 300 IN A 192.0.2.1")}
+		}
+		w.WriteMsg(m)
+	})
+}
+
+func TestResolveAddressesUsesNameserverPort(t *testing.T) {
+	port := authServer(t)
+
+	tests := []struct {
+		nameserverPort int
+		want           string
+	}{
+		{0, "127.0.0.1:53"},
+		{5360, "127.0.0.1:5360"},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig(port)
+		cfg.NameserverPort = tt.nameserverPort
+
+		addresses := resolveAddresses("ns.example.", IPv4, cfg, testAddr(port))
+		if len(addresses) != 1 || addresses[0] != tt.want {
+			t.Errorf("nameserver port %d: addresses = %v, want [%s]", tt.nameserverPort, addresses, tt.want)
+		}
+	}
+}
+
+func TestQueryAuthoritative(t *testing.T) {
+	port := authServer(t)
+	cfg := testConfig(port)
+	cfg.NameserverPort = port
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("www.example.", dns.TypeA)
+	spec := QuerySpec{Domain: "www.example", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+
+	session := NewSession()
+	defer session.Close()
+	auth := session.queryAuthoritative(msg, spec, cfg, testAddr(port))
+
+	if auth.Error != "" || auth.Zone != "example." {
+		t.Fatalf("zone = %q, error = %q", auth.Zone, auth.Error)
+	}
+	if len(auth.Servers) != 1 || auth.Servers[0].Address != testAddr(port) {
+		t.Fatalf("servers = %+v, want ns.example. at %s", auth.Servers, testAddr(port))
+	}
+	if !auth.Consistent || !auth.Servers[0].Authoritative {
+		t.Errorf("result = %+v, want a consistent authoritative answer", auth)
+	}
+}
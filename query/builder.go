@@ -1,20 +1,20 @@
 package query
 
 import (
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"strings"
-	
-)
 
-const (
-	ClassIN = 1
+	"github.com/miekg/dns"
 )
 
-// BuildDNSQuery creates a raw DNS query packet for the given domain and query type
-func BuildDNSQuery(domain string, queryType QueryType) ([]byte, error) {
+// BuildDNSQuery creates a raw DNS query packet for the given domain, query type and class
+func BuildDNSQuery(domain string, queryType QueryType, class QueryClass) ([]byte, error) {
 	if domain == "" {
 		return nil, errors.New("domain cannot be empty")
 	}
@@ -22,7 +22,7 @@ func BuildDNSQuery(domain string, queryType QueryType) ([]byte, error) {
 	txID := uint16(rand.Intn(65536))
 	header := buildDNSHeader(txID, 1)
 
-	question, err := buildDNSQuestion(domain, queryType.WireValue(), ClassIN)
+	question, err := buildDNSQuestion(domain, queryType.WireValue(), class.WireValue())
 	if err != nil {
 		return nil, err
 	}
@@ -91,26 +91,30 @@ func encodeDomainName(domain string) ([]byte, error) {
 	return encoded, nil
 }
 
-// ParseDNSResponse extracts RCODE and all record data from DNS response
-// Parses answer, authority, and additional sections
-func ParseDNSResponse(response []byte) (rcode int, answers []string, err error) {
+// ResponseSections holds the record data of each section of a DNS response
+type ResponseSections struct {
+	Answer     []string
+	Authority  []string
+	Additional []string
+}
+
+// ParseDNSResponse extracts RCODE and the record data of the answer, authority
+// and additional sections of a DNS response. Sections are parsed for every
+// RCODE, so negative answers keep their SOA in the authority section.
+func ParseDNSResponse(response []byte) (rcode int, sections ResponseSections, err error) {
 	if len(response) < 12 {
-		return 0, nil, errors.New("response too short")
+		return 0, sections, errors.New("response too short")
 	}
 
 	flags := binary.BigEndian.Uint16(response[2:4])
 	rcode = int(flags & 0x000F)
 
 	questionCount := binary.BigEndian.Uint16(response[4:6])
-	answerCount := binary.BigEndian.Uint16(response[6:8])
-	authorityCount := binary.BigEndian.Uint16(response[8:10])
-	additionalCount := binary.BigEndian.Uint16(response[10:12])
+	answerCount := int(binary.BigEndian.Uint16(response[6:8]))
+	authorityCount := int(binary.BigEndian.Uint16(response[8:10]))
+	additionalCount := int(binary.BigEndian.Uint16(response[10:12]))
 
-	totalRecords := int(answerCount) + int(authorityCount) + int(additionalCount)
-
-	if rcode != 0 {
-		return rcode, []string{}, nil
-	}
+	totalRecords := answerCount + authorityCount + additionalCount
 
 	offset := 12
 
@@ -118,14 +122,16 @@ func ParseDNSResponse(response []byte) (rcode int, answers []string, err error)
 	for i := 0; i < int(questionCount); i++ {
 		newOffset, err := skipDomainName(response, offset)
 		if err != nil {
-			return rcode, nil, err
+			return rcode, sections, err
 		}
 		offset = newOffset
 		offset += 4
 	}
 
-	// Parse all sections
-	answers = make([]string, 0)
+	// Parse all sections, keeping each record in the section it came from
+	sections.Answer = make([]string, 0, answerCount)
+	sections.Authority = make([]string, 0, authorityCount)
+	sections.Additional = make([]string, 0, additionalCount)
 
 	for i := 0; i < totalRecords; i++ {
 		if offset >= len(response) {
@@ -156,13 +162,20 @@ func ParseDNSResponse(response []byte) (rcode int, answers []string, err error)
 
 		record := parseRecord(response, offset, recordType, rdLength)
 		if record != "" {
-			answers = append(answers, record)
+			switch {
+			case i < answerCount:
+				sections.Answer = append(sections.Answer, record)
+			case i < answerCount+authorityCount:
+				sections.Authority = append(sections.Authority, record)
+			default:
+				sections.Additional = append(sections.Additional, record)
+			}
 		}
 
 		offset += int(rdLength)
 	}
 
-	return rcode, answers, nil
+	return rcode, sections, nil
 }
 
 // parseRecord extracts human-readable data from a DNS record
@@ -239,11 +252,176 @@ func parseRecord(data []byte, offset int, recordType uint16, rdLength uint16) st
 				return fmt.Sprintf("SRV:%d %d %d %s", priority, weight, port, target)
 			}
 		}
+
+	case QueryTypeCAA:
+		if rdLength >= 2 {
+			flags := data[offset]
+			tagLen := int(data[offset+1])
+			if 2+tagLen <= int(rdLength) {
+				tag := string(data[offset+2 : offset+2+tagLen])
+				value := string(data[offset+2+tagLen : offset+int(rdLength)])
+				return fmt.Sprintf("CAA:%d %s \"%s\"", flags, tag, value)
+			}
+		}
+
+	case QueryTypeDNSKEY:
+		if rdLength >= 4 {
+			return formatDNSKEY(&dns.DNSKEY{
+				Flags:     binary.BigEndian.Uint16(data[offset : offset+2]),
+				Protocol:  data[offset+2],
+				Algorithm: data[offset+3],
+				PublicKey: base64.StdEncoding.EncodeToString(data[offset+4 : offset+int(rdLength)]),
+			})
+		}
+
+	case QueryTypeDS:
+		if rdLength >= 4 {
+			return formatDS(&dns.DS{
+				KeyTag:     binary.BigEndian.Uint16(data[offset : offset+2]),
+				Algorithm:  data[offset+2],
+				DigestType: data[offset+3],
+				Digest:     hex.EncodeToString(data[offset+4 : offset+int(rdLength)]),
+			})
+		}
+
+	case QueryTypeSSHFP:
+		if rdLength >= 2 {
+			algorithm := data[offset]
+			fpType := data[offset+1]
+			fingerprint := hex.EncodeToString(data[offset+2 : offset+int(rdLength)])
+			return fmt.Sprintf("SSHFP:%d %d %s", algorithm, fpType, fingerprint)
+		}
+
+	case QueryTypeTLSA:
+		if rdLength >= 3 {
+			usage := data[offset]
+			selector := data[offset+1]
+			matchingType := data[offset+2]
+			association := hex.EncodeToString(data[offset+3 : offset+int(rdLength)])
+			return fmt.Sprintf("TLSA:%d %d %d %s", usage, selector, matchingType, association)
+		}
+
+	case QueryTypeNAPTR:
+		if rdLength >= 7 {
+			end := offset + int(rdLength)
+			order := binary.BigEndian.Uint16(data[offset : offset+2])
+			preference := binary.BigEndian.Uint16(data[offset+2 : offset+4])
+			pos := offset + 4
+
+			var fields [3]string
+			ok := true
+			for i := range fields {
+				fields[i], pos, ok = readCharString(data, pos, end)
+				if !ok {
+					break
+				}
+			}
+
+			if ok {
+				replacement, err := readDomainName(data, pos)
+				if err == nil {
+					return fmt.Sprintf("NAPTR:%d %d \"%s\" \"%s\" \"%s\" %s",
+						order, preference, fields[0], fields[1], fields[2], replacement)
+				}
+			}
+		}
+
+	case QueryTypeSVCB, QueryTypeHTTPS:
+		if rdLength >= 3 {
+			end := offset + int(rdLength)
+			priority := binary.BigEndian.Uint16(data[offset : offset+2])
+			target, err := readDomainName(data, offset+2)
+			if err != nil {
+				break
+			}
+			pos, err := skipDomainName(data, offset+2)
+			if err != nil {
+				break
+			}
+
+			parts := []string{fmt.Sprintf("%d %s", priority, target)}
+			for pos+4 <= end {
+				key := binary.BigEndian.Uint16(data[pos : pos+2])
+				length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+				pos += 4
+				if pos+length > end {
+					break
+				}
+				parts = append(parts, formatSvcParam(key, data[pos:pos+length]))
+				pos += length
+			}
+
+			return fmt.Sprintf("%s:%s", QueryType(recordType).String(), strings.Join(parts, " "))
+		}
 	}
 
 	return ""
 }
 
+// readCharString reads a length-prefixed <character-string> ending before end
+func readCharString(data []byte, offset int, end int) (string, int, bool) {
+	if offset >= end {
+		return "", offset, false
+	}
+	length := int(data[offset])
+	offset++
+	if offset+length > end {
+		return "", offset, false
+	}
+	return string(data[offset : offset+length]), offset + length, true
+}
+
+// formatSvcParam renders one SVCB/HTTPS SvcParam (RFC 9460 section 14.3.2) as key=value
+func formatSvcParam(key uint16, value []byte) string {
+	switch key {
+	case 0: // mandatory
+		var keys []string
+		for i := 0; i+2 <= len(value); i += 2 {
+			keys = append(keys, svcParamKeyName(binary.BigEndian.Uint16(value[i:i+2])))
+		}
+		return "mandatory=" + strings.Join(keys, ",")
+	case 1: // alpn
+		var protocols []string
+		for pos := 0; pos < len(value); {
+			protocol, next, ok := readCharString(value, pos, len(value))
+			if !ok {
+				break
+			}
+			protocols = append(protocols, protocol)
+			pos = next
+		}
+		return "alpn=" + strings.Join(protocols, ",")
+	case 2:
+		return "no-default-alpn"
+	case 3:
+		if len(value) == 2 {
+			return fmt.Sprintf("port=%d", binary.BigEndian.Uint16(value))
+		}
+	case 4, 6: // ipv4hint, ipv6hint
+		size := net.IPv4len
+		if key == 6 {
+			size = net.IPv6len
+		}
+		var hints []string
+		for i := 0; i+size <= len(value); i += size {
+			hints = append(hints, net.IP(value[i:i+size]).String())
+		}
+		return svcParamKeyName(key) + "=" + strings.Join(hints, ",")
+	case 5: // ech
+		return "ech=" + base64.StdEncoding.EncodeToString(value)
+	}
+
+	return fmt.Sprintf("%s=%s", svcParamKeyName(key), hex.EncodeToString(value))
+}
+
+func svcParamKeyName(key uint16) string {
+	names := []string{"mandatory", "alpn", "no-default-alpn", "port", "ipv4hint", "ech", "ipv6hint"}
+	if int(key) < len(names) {
+		return names[key]
+	}
+	return fmt.Sprintf("key%d", key)
+}
+
 // readDomainName reads and reconstructs a domain name from DNS response
 func readDomainName(data []byte, offset int) (string, error) {
 	var parts []string
@@ -330,22 +508,27 @@ func GetAllQueryTypes() []QueryType {
         QueryTypeCNAME,
         QueryTypePTR,
         QueryTypeSRV,
+        QueryTypeCAA,
+        QueryTypeDNSKEY,
+        QueryTypeDS,
+        QueryTypeHTTPS,
+        QueryTypeSVCB,
+        QueryTypeTLSA,
+        QueryTypeNAPTR,
+        QueryTypeSSHFP,
         // QueryTypeANY deliberately excluded - it's redundant with --query-all
     }
 }
 
-// ExpandToAllTypes creates query specs for all record types for a given domain
-func ExpandToAllTypes(domain string, transport Transport, ipVersion IPVersion) []QuerySpec {
+// ExpandToAllTypes creates query specs for all record types for a given domain.
+// All other settings (transport, network, EDNS) are copied from base.
+func ExpandToAllTypes(base QuerySpec) []QuerySpec {
     allTypes := GetAllQueryTypes()
     specs := make([]QuerySpec, len(allTypes))
     
     for i, qType := range allTypes {
-        specs[i] = QuerySpec{
-            Domain:    domain,
-            QueryType: qType,
-            Transport: transport,
-            IPVersion: ipVersion,
-        }
+        specs[i] = base
+        specs[i].QueryType = qType
     }
     
     return specs
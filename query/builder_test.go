@@ -0,0 +1,114 @@
+package query
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// packRData packs rr without compression and returns the message bytes with
+// the offset, type and length of its RDATA, as parseRecord receives them
+func packRData(t *testing.T, s string) ([]byte, int, uint16, uint16) {
+	t.Helper()
+
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	buf := make([]byte, dns.MaxMsgSize)
+	end, err := dns.PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("pack %q: %v", s, err)
+	}
+	nameLen, err := dns.PackDomainName(rr.Header().Name, make([]byte, 256), 0, nil, false)
+	if err != nil {
+		t.Fatalf("pack name: %v", err)
+	}
+	rdLength := binary.BigEndian.Uint16(buf[nameLen+8 : nameLen+10])
+	return buf[:end], nameLen + 10, rr.Header().Rrtype, rdLength
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		rr       string
+		rdLength int // Overrides the packed length when set, to simulate truncated RDATA
+		want     string
+	}{
+		{
+			name: "CAA",
+			rr:   `example.com. 300 IN CAA 128 issue "letsencrypt.org"`,
+			want: `CAA:128 issue "letsencrypt.org"`,
+		},
+		{
+			name:     "CAA tag longer than RDATA",
+			rr:       `example.com. 300 IN CAA 0 issuewild "ca.example"`,
+			rdLength: 5,
+			want:     "",
+		},
+		{
+			name: "DNSKEY",
+			rr:   `dskey.example.com. 86400 IN DNSKEY 256 3 5 AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw==`,
+			want: "DNSKEY:256 3 RSASHA1 AQOeiiR0GOMYkDshWoSKz9XzfwJr1AYtsmx3TGkJaNXVbfi/2pHm822aJ5iI9BMzNXxeYCmZDRD99WYwYqUSdjMmmAphXdvxegXd/M5+X7OrzKBaMbCVdFLUUh6DhweJBjEVv5f2wwjM9XzcnOf+EPbtG9DMBmADjFDc2w/rljwvFw== (tag 60485)",
+		},
+		{
+			name: "DS",
+			rr:   `dskey.example.com. 86400 IN DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118`,
+			want: "DS:60485 RSASHA1 1 2bb183af5f22588179a53b0a98631fad1a292118",
+		},
+		{
+			name: "NAPTR",
+			rr:   `example.com. 300 IN NAPTR 100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+			want: `NAPTR:100 10 "S" "SIP+D2U" "" _sip._udp.example.com`,
+		},
+		{
+			name:     "NAPTR truncated in a string",
+			rr:       `example.com. 300 IN NAPTR 100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+			rdLength: 8,
+			want:     "",
+		},
+		{
+			name: "HTTPS service mode",
+			rr:   `example.com. 300 IN HTTPS 1 . alpn="h2,h3" port=8443 ipv4hint=192.0.2.1,192.0.2.2`,
+			want: "HTTPS:1 . alpn=h2,h3 port=8443 ipv4hint=192.0.2.1,192.0.2.2",
+		},
+		{
+			name: "SVCB alias mode",
+			rr:   `_dns.example.com. 300 IN SVCB 0 dns.example.com.`,
+			want: "SVCB:0 dns.example.com",
+		},
+		{
+			name: "SVCB unknown key",
+			rr:   `example.com. 300 IN SVCB 1 svc.example.com. key65000=abc`,
+			want: "SVCB:1 svc.example.com key65000=616263",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, offset, rrtype, rdLength := packRData(t, tt.rr)
+			if tt.rdLength > 0 {
+				rdLength = uint16(tt.rdLength)
+			}
+			if got := parseRecord(data, offset, rrtype, rdLength); got != tt.want {
+				t.Errorf("parseRecord = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecordMatchesParseAnswers(t *testing.T) {
+	// Records decoded from raw bytes and through miekg/dns must read the same
+	for _, s := range []string{
+		`dskey.example.com. 86400 IN DNSKEY 257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ==`,
+		`dskey.example.com. 86400 IN DS 60485 5 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A`,
+		`example.com. 300 IN CAA 0 issue "letsencrypt.org"`,
+	} {
+		data, offset, rrtype, rdLength := packRData(t, s)
+		_, records := parseAnswers([]dns.RR{mustRR(t, s)})
+		if got := parseRecord(data, offset, rrtype, rdLength); len(records) != 1 || got != records[0] {
+			t.Errorf("parseRecord = %q, parseAnswers = %q", got, records)
+		}
+	}
+}
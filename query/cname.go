@@ -0,0 +1,151 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// resolveCNAMEChain records the CNAME chain in response, starting at the
+// queried name. With --follow-cname, a chain that ends without records of the
+// queried type is continued with follow-up queries. It returns nil when the
+// answer contains no alias for the queried name.
+func resolveCNAMEChain(response *dns.Msg, spec QuerySpec, cfg config.Config, server string, resolver string) *result.CNAMEChain {
+	maxDepth := cnameDepth(cfg)
+
+	chain := &result.CNAMEChain{Final: dns.Fqdn(spec.Domain)}
+	extendChain(chain, response.Answer, server, maxDepth)
+	if len(chain.Hops) == 0 {
+		return nil
+	}
+
+	qtype := uint16(spec.QueryType)
+	if qtype == dns.TypeCNAME || qtype == dns.TypeANY {
+		// The alias itself is the answer to these queries
+		chain.Complete = true
+		return chain
+	}
+	chain.Complete = len(chainRecords(response.Answer, chain.Final, qtype)) > 0
+
+	if !cfg.FollowCNAME || chain.Complete || chain.Loop || chain.TooLong {
+		return chain
+	}
+	if response.Rcode != dns.RcodeSuccess || !spec.Class.IsIN() {
+		return chain
+	}
+
+	for !chain.Complete && !chain.Loop && !chain.TooLong {
+		resp, answeredBy, err := chainLookup(chain.Final, spec, cfg, resolver)
+		chain.FollowUps++
+		if err != nil {
+			chain.Error = fmt.Sprintf("follow-up query for %s failed: %v", chain.Final, err)
+			break
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			chain.Error = fmt.Sprintf("%s answered %s for %s", answeredBy, rcodeString(resp.Rcode), chain.Final)
+			break
+		}
+
+		hops := len(chain.Hops)
+		extendChain(chain, resp.Answer, answeredBy, maxDepth)
+		if records := chainRecords(resp.Answer, chain.Final, qtype); len(records) > 0 {
+			chain.Complete = true
+			chain.Answers = newRecords(records)
+		} else if len(chain.Hops) == hops {
+			chain.Error = fmt.Sprintf("no %s records for %s", spec.QueryType, chain.Final)
+			break
+		}
+	}
+
+	return chain
+}
+
+// cnameDepth returns the configured chain depth limit, or the default when unset
+func cnameDepth(cfg config.Config) int {
+	if cfg.MaxCNAMEDepth <= 0 {
+		return config.DefaultMaxCNAMEDepth
+	}
+	return cfg.MaxCNAMEDepth
+}
+
+// extendChain appends the hops in rrs that continue chain from its final
+// name, stopping when a target repeats an earlier name or the chain grows
+// past maxDepth hops
+func extendChain(chain *result.CNAMEChain, rrs []dns.RR, server string, maxDepth int) {
+	aliases := make(map[string]*dns.CNAME)
+	for _, rr := range rrs {
+		if cname, ok := rr.(*dns.CNAME); ok {
+			aliases[dns.CanonicalName(cname.Hdr.Name)] = cname
+		}
+	}
+
+	seen := map[string]bool{dns.CanonicalName(chain.Final): true}
+	for _, hop := range chain.Hops {
+		seen[dns.CanonicalName(hop.Name)] = true
+	}
+
+	for {
+		cname, ok := aliases[dns.CanonicalName(chain.Final)]
+		if !ok {
+			return
+		}
+
+		chain.Hops = append(chain.Hops, result.CNAMEHop{
+			Name:   cname.Hdr.Name,
+			Target: cname.Target,
+			TTL:    cname.Hdr.Ttl,
+			Server: server,
+		})
+		chain.Final = cname.Target
+
+		target := dns.CanonicalName(cname.Target)
+		if seen[target] {
+			chain.Loop = true
+			return
+		}
+		seen[target] = true
+
+		if len(chain.Hops) > maxDepth {
+			chain.TooLong = true
+			return
+		}
+	}
+}
+
+// chainRecords returns the records of type qtype owned by name
+func chainRecords(rrs []dns.RR, name string, qtype uint16) []dns.RR {
+	var records []dns.RR
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		if hdr.Rrtype == qtype && dns.CanonicalName(hdr.Name) == dns.CanonicalName(name) {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
+
+// chainLookup queries for the next name of a chain, through the root in
+// --trace mode and through the resolver otherwise. It also returns the
+// address of the server that answered.
+func chainLookup(name string, spec QuerySpec, cfg config.Config, resolver string) (*dns.Msg, string, error) {
+	if !cfg.Trace {
+		resp, err := resolverLookup(dns.Fqdn(name), uint16(spec.QueryType), spec.IPVersion, cfg, resolver)
+		return resp, resolver, err
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), uint16(spec.QueryType))
+	resp, steps, err := traceQuery(msg, spec, cfg)
+	return resp, tracedServer(steps, resolver), err
+}
+
+// tracedServer returns the address of the last server asked in a trace, which
+// is the one whose response was used
+func tracedServer(steps []result.TraceStep, fallback string) string {
+	if len(steps) == 0 {
+		return fallback
+	}
+	return steps[len(steps)-1].Address
+}
@@ -0,0 +1,297 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// parentReferral is the delegation of a zone as seen by its parent's servers
+type parentReferral struct {
+	nameservers []string
+	glue        map[string][]string // Nameserver name -> glue addresses
+	issues      []string
+}
+
+// checkDelegation compares the NS set in the parent zone's referral with the
+// NS set served by the zone itself, checks in-bailiwick glue against the
+// zone's A/AAAA records and reports delegated servers that are lame. Results
+// are cached in the session per zone.
+func (s *Session) checkDelegation(spec QuerySpec, cfg config.Config, resolver string) *result.DelegationResult {
+	zs := s.zoneServers(dns.Fqdn(spec.Domain), spec.IPVersion, cfg, resolver)
+
+	key := resolver + "|" + zs.zone
+	if zs.err == nil {
+		if delegation, ok := s.delegations[key]; ok {
+			return delegation
+		}
+	}
+
+	delegation := &result.DelegationResult{
+		Zone:     zs.zone,
+		ParentNS: []string{},
+		ChildNS:  []string{},
+		Servers:  []result.DelegationServer{},
+	}
+	if zs.err != nil {
+		delegation.Error = zs.err.Error()
+		return delegation
+	}
+	if zs.zone == "." {
+		delegation.Error = "the root zone has no parent delegation"
+		return delegation
+	}
+
+	ps := s.zoneServers(parentZone(zs.zone), spec.IPVersion, cfg, resolver)
+	delegation.Parent = ps.zone
+	if ps.err != nil {
+		delegation.Error = fmt.Sprintf("parent zone lookup failed: %v", ps.err)
+		return delegation
+	}
+
+	referral, err := queryParentReferral(zs.zone, ps.servers, spec, cfg)
+	if err != nil {
+		delegation.Error = err.Error()
+		return delegation
+	}
+	delegation.ParentNS = referral.nameservers
+	delegation.Issues = append(delegation.Issues, referral.issues...)
+
+	// Every delegated server must answer authoritatively for the zone
+	childNS := make(map[string]bool)
+	var authServer string
+	for _, nsName := range referral.nameservers {
+		addresses := referral.glue[nsName]
+		if len(addresses) == 0 {
+			addresses = resolveAddresses(nsName, spec.IPVersion, cfg, resolver)
+		} else {
+			addresses = withPort(addresses, nameserverPort(cfg))
+		}
+		if len(addresses) == 0 {
+			delegation.Issues = append(delegation.Issues, fmt.Sprintf("lame: %s has no addresses", nsName))
+			continue
+		}
+
+		for _, address := range addresses {
+			server, names := queryChildNS(zs.zone, traceServer{name: nsName, address: address}, spec, cfg)
+			delegation.Servers = append(delegation.Servers, server)
+			if server.Lame {
+				delegation.Issues = append(delegation.Issues, fmt.Sprintf("lame: %s (%s) %s", server.Server, server.Address, server.Reason))
+				continue
+			}
+			if authServer == "" {
+				authServer = address
+			}
+			for _, name := range names {
+				childNS[name] = true
+			}
+		}
+	}
+
+	for name := range childNS {
+		delegation.ChildNS = append(delegation.ChildNS, name)
+	}
+	sort.Strings(delegation.ChildNS)
+
+	if authServer == "" {
+		delegation.Issues = append(delegation.Issues, "no delegated server answered authoritatively")
+	} else {
+		for _, name := range delegation.ParentNS {
+			if !childNS[name] {
+				delegation.MissingAtChild = append(delegation.MissingAtChild, name)
+			}
+		}
+		for _, name := range delegation.ChildNS {
+			if !slices.Contains(delegation.ParentNS, name) {
+				delegation.MissingAtParent = append(delegation.MissingAtParent, name)
+			}
+		}
+		if len(delegation.MissingAtChild) > 0 {
+			delegation.Issues = append(delegation.Issues, fmt.Sprintf("delegated by %s but not listed by the zone: %s",
+				delegation.Parent, strings.Join(delegation.MissingAtChild, ", ")))
+		}
+		if len(delegation.MissingAtParent) > 0 {
+			delegation.Issues = append(delegation.Issues, fmt.Sprintf("listed by the zone but not delegated by %s: %s",
+				delegation.Parent, strings.Join(delegation.MissingAtParent, ", ")))
+		}
+
+		delegation.Glue = checkGlue(zs.zone, referral, authServer, spec, cfg)
+		for _, glue := range delegation.Glue {
+			switch {
+			case len(glue.Glue) == 0:
+				delegation.Issues = append(delegation.Issues, fmt.Sprintf("missing glue for in-bailiwick %s", glue.Nameserver))
+			case !glue.Match:
+				delegation.Issues = append(delegation.Issues, fmt.Sprintf("glue for %s %v does not match the zone's records %v",
+					glue.Nameserver, glue.Glue, glue.Child))
+			}
+		}
+	}
+
+	delegation.Consistent = len(delegation.Issues) == 0
+	s.delegations[key] = delegation
+	return delegation
+}
+
+// queryParentReferral asks every server of the parent zone for the zone's NS
+// set with RD=0 and collects the delegated nameservers and their glue
+func queryParentReferral(zone string, servers []traceServer, spec QuerySpec, cfg config.Config) (*parentReferral, error) {
+	query := new(dns.Msg)
+	query.SetQuestion(zone, dns.TypeNS)
+	query.RecursionDesired = false
+
+	referral := &parentReferral{glue: make(map[string][]string)}
+	nsSets := make(map[string][]string)
+	var lastErr error
+
+	for _, server := range servers {
+		response, _, err := exchangeAuthServer(query, server.address, spec.Transport, cfg)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		// A parent that also serves the child answers authoritatively instead of referring
+		var names []string
+		for _, rr := range append(response.Answer, response.Ns...) {
+			if ns, ok := rr.(*dns.NS); ok && dns.CanonicalName(ns.Hdr.Name) == zone {
+				names = append(names, dns.CanonicalName(ns.Ns))
+			}
+		}
+		sort.Strings(names)
+		names = slices.Compact(names)
+		if len(names) == 0 {
+			lastErr = fmt.Errorf("%s (%s) returned no delegation for %s", server.name, server.address, zone)
+			continue
+		}
+		nsSets[strings.Join(names, " ")] = append(nsSets[strings.Join(names, " ")], server.address)
+
+		for _, rr := range response.Extra {
+			owner := dns.CanonicalName(rr.Header().Name)
+			if !slices.Contains(names, owner) {
+				continue
+			}
+			for _, ip := range addressRecords([]dns.RR{rr}) {
+				if !slices.Contains(referral.glue[owner], ip) {
+					referral.glue[owner] = append(referral.glue[owner], ip)
+				}
+			}
+		}
+	}
+
+	if len(nsSets) == 0 {
+		return nil, fmt.Errorf("no parent server returned a delegation: %v", lastErr)
+	}
+
+	union := make(map[string]bool)
+	for set := range nsSets {
+		for _, name := range strings.Fields(set) {
+			union[name] = true
+		}
+	}
+	for name := range union {
+		referral.nameservers = append(referral.nameservers, name)
+	}
+	sort.Strings(referral.nameservers)
+
+	if len(nsSets) > 1 {
+		referral.issues = append(referral.issues, "parent servers disagree on the NS set: "+describeGroups(nsSets))
+	}
+	for _, addresses := range referral.glue {
+		sort.Strings(addresses)
+	}
+
+	return referral, nil
+}
+
+// queryChildNS asks one delegated server for the zone's NS set with RD=0. The
+// server is lame if it fails to respond or answers without the AA flag.
+func queryChildNS(zone string, server traceServer, spec QuerySpec, cfg config.Config) (result.DelegationServer, []string) {
+	query := new(dns.Msg)
+	query.SetQuestion(zone, dns.TypeNS)
+	query.RecursionDesired = false
+
+	delegationServer := result.DelegationServer{
+		Server:  server.name,
+		Address: server.address,
+	}
+
+	response, latency, err := exchangeAuthServer(query, server.address, spec.Transport, cfg)
+	delegationServer.LatencyMs = latency
+
+	switch {
+	case err != nil:
+		delegationServer.Lame = true
+		delegationServer.Reason = err.Error()
+	case response.Rcode != dns.RcodeSuccess:
+		delegationServer.Lame = true
+		delegationServer.Reason = fmt.Sprintf("answered %s", dns.RcodeToString[response.Rcode])
+	case !response.Authoritative:
+		delegationServer.Lame = true
+		delegationServer.Reason = "answered without the AA flag"
+	}
+	if delegationServer.Lame {
+		return delegationServer, nil
+	}
+
+	var names []string
+	for _, rr := range response.Answer {
+		if ns, ok := rr.(*dns.NS); ok && dns.CanonicalName(ns.Hdr.Name) == zone {
+			names = append(names, dns.CanonicalName(ns.Ns))
+		}
+	}
+	return delegationServer, names
+}
+
+// checkGlue compares the glue of every in-bailiwick nameserver with the A and
+// AAAA records served by the zone's authoritative server at address
+func checkGlue(zone string, referral *parentReferral, address string, spec QuerySpec, cfg config.Config) []result.GlueCheck {
+	var checks []result.GlueCheck
+
+	for _, nsName := range referral.nameservers {
+		if !dns.IsSubDomain(zone, nsName) {
+			continue
+		}
+
+		check := result.GlueCheck{
+			Nameserver: nsName,
+			Glue:       referral.glue[nsName],
+			Child:      []string{},
+		}
+		if check.Glue == nil {
+			check.Glue = []string{}
+		}
+
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			query := new(dns.Msg)
+			query.SetQuestion(nsName, qtype)
+			query.RecursionDesired = false
+
+			response, _, err := exchangeAuthServer(query, address, spec.Transport, cfg)
+			if err != nil {
+				continue
+			}
+			check.Child = append(check.Child, addressRecords(response.Answer)...)
+		}
+		sort.Strings(check.Child)
+
+		check.Match = slices.Equal(check.Glue, check.Child)
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// withPort joins each address with port
+func withPort(ips []string, port string) []string {
+	addresses := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, net.JoinHostPort(ip, port))
+	}
+	return addresses
+}
@@ -0,0 +1,208 @@
+package query
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// delegationSetup describes sub.example. as delegated by example.
+type delegationSetup struct {
+	parentNS  []string            // NS set in the parent's referral
+	glue      map[string][]string // Glue in the parent's referral
+	childNS   []string            // NS set served by the zone
+	addresses map[string][]string // A records served by the resolver and the zone
+}
+
+// delegationServer answers on several loopback addresses at once:
+// 127.0.0.1 is the resolver, 127.0.0.2 serves example., 127.0.0.3 serves
+// sub.example. and 127.0.0.4 is a lame server for it
+func delegationServer(t *testing.T, setup delegationSetup) int {
+	nsRecords := func(names []string) []dns.RR {
+		var rrs []dns.RR
+		for _, name := range names {
+			rrs = append(rrs, &dns.NS{Hdr: dns.RR_Header{Name: "sub.example.", Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 3600}, Ns: name})
+		}
+		return rrs
+	}
+	aRecords := func(name string, ips []string) []dns.RR {
+		var rrs []dns.RR
+		for _, ip := range ips {
+			rrs = append(rrs, mustRR(t, name+" 3600 IN A "+ip))
+		}
+		return rrs
+	}
+
+	return startLoopbackServers(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		host, _, _ := strings.Cut(w.LocalAddr().String(), ":")
+
+		switch host {
+		case "127.0.0.1":
+			switch {
+			case q.Qtype == dns.TypeNS && q.Name == "example.":
+				m.Answer = []dns.RR{mustRR(t, "example. 3600 IN NS ns.example.")}
+			case q.Qtype == dns.TypeNS && q.Name == "sub.example.":
+				m.Answer = nsRecords(setup.childNS)
+			case q.Qtype == dns.TypeA && q.Name == "ns.example.":
+				m.Answer = aRecords(q.Name, []string{"127.0.0.2"})
+			case q.Qtype == dns.TypeA:
+				m.Answer = aRecords(q.Name, setup.addresses[q.Name])
+			}
+		case "127.0.0.2":
+			if q.Qtype == dns.TypeNS && q.Name == "sub.example." {
+				m.Ns = nsRecords(setup.parentNS)
+				for _, name := range setup.parentNS {
+					m.Extra = append(m.Extra, aRecords(name, setup.glue[name])...)
+				}
+			}
+		case "127.0.0.3":
+			m.Authoritative = true
+			switch q.Qtype {
+			case dns.TypeNS:
+				m.Answer = nsRecords(setup.childNS)
+			case dns.TypeA:
+				m.Answer = aRecords(q.Name, setup.addresses[q.Name])
+			}
+		case "127.0.0.4":
+			m.Ns = nsRecords(setup.parentNS)
+		}
+		w.WriteMsg(m)
+	}, "127.0.0.2", "127.0.0.3", "127.0.0.4")
+}
+
+func TestCheckDelegation(t *testing.T) {
+	const ns1, ns2, ns3 = "ns1.sub.example.", "ns2.sub.example.", "ns3.sub.example."
+
+	tests := []struct {
+		name            string
+		setup           delegationSetup
+		wantServers     int
+		wantLame        []string
+		wantGlue        []bool // Match of each glue check
+		missingAtChild  []string
+		missingAtParent []string
+		wantIssues      []string // Issue prefixes, in order
+	}{
+		{
+			name: "consistent",
+			setup: delegationSetup{
+				parentNS:  []string{ns1},
+				glue:      map[string][]string{ns1: {"127.0.0.3"}},
+				childNS:   []string{ns1},
+				addresses: map[string][]string{ns1: {"127.0.0.3"}},
+			},
+			wantServers: 1,
+			wantGlue:    []bool{true},
+		},
+		{
+			name: "lame server",
+			setup: delegationSetup{
+				parentNS:  []string{ns1, ns2},
+				glue:      map[string][]string{ns1: {"127.0.0.3"}, ns2: {"127.0.0.4"}},
+				childNS:   []string{ns1, ns2},
+				addresses: map[string][]string{ns1: {"127.0.0.3"}, ns2: {"127.0.0.4"}},
+			},
+			wantServers: 2,
+			wantLame:    []string{ns2},
+			wantGlue:    []bool{true, true},
+			wantIssues:  []string{"lame: ns2.sub.example. (127.0.0.4:"},
+		},
+		{
+			name: "missing glue",
+			setup: delegationSetup{
+				parentNS:  []string{ns1},
+				childNS:   []string{ns1},
+				addresses: map[string][]string{ns1: {"127.0.0.3"}},
+			},
+			wantServers: 1,
+			wantGlue:    []bool{false},
+			wantIssues:  []string{"missing glue for in-bailiwick ns1.sub.example."},
+		},
+		{
+			name: "glue differs from the zone",
+			setup: delegationSetup{
+				parentNS:  []string{ns1},
+				glue:      map[string][]string{ns1: {"127.0.0.3"}},
+				childNS:   []string{ns1},
+				addresses: map[string][]string{ns1: {"127.0.0.3", "192.0.2.3"}},
+			},
+			wantServers: 1,
+			wantGlue:    []bool{false},
+			wantIssues:  []string{"glue for ns1.sub.example. [127.0.0.3] does not match the zone's records [127.0.0.3 192.0.2.3]"},
+		},
+		{
+			name: "parent and child NS sets differ",
+			setup: delegationSetup{
+				parentNS:  []string{"ns.other.example.", ns1},
+				glue:      map[string][]string{ns1: {"127.0.0.3"}},
+				childNS:   []string{ns1, ns3},
+				addresses: map[string][]string{ns1: {"127.0.0.3"}, "ns.other.example.": {"127.0.0.3"}},
+			},
+			wantServers:     2,
+			wantGlue:        []bool{true},
+			missingAtChild:  []string{"ns.other.example."},
+			missingAtParent: []string{ns3},
+			wantIssues: []string{
+				"delegated by example. but not listed by the zone: ns.other.example.",
+				"listed by the zone but not delegated by example.: ns3.sub.example.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := delegationServer(t, tt.setup)
+			cfg := testConfig(port)
+			cfg.NameserverPort = port
+
+			session := NewSession()
+			defer session.Close()
+			spec := QuerySpec{Domain: "www.sub.example", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+			delegation := session.checkDelegation(spec, cfg, testAddr(port))
+
+			if delegation.Error != "" || delegation.Zone != "sub.example." || delegation.Parent != "example." {
+				t.Fatalf("zone %q, parent %q, error %q", delegation.Zone, delegation.Parent, delegation.Error)
+			}
+			if !slices.Equal(delegation.ParentNS, tt.setup.parentNS) {
+				t.Errorf("parent NS = %v, want %v", delegation.ParentNS, tt.setup.parentNS)
+			}
+
+			var lame []string
+			for _, server := range delegation.Servers {
+				if server.Lame {
+					lame = append(lame, server.Server)
+				}
+			}
+			if len(delegation.Servers) != tt.wantServers || !slices.Equal(lame, tt.wantLame) {
+				t.Errorf("servers = %+v, want %d with lame %v", delegation.Servers, tt.wantServers, tt.wantLame)
+			}
+
+			var glue []bool
+			for _, check := range delegation.Glue {
+				glue = append(glue, check.Match)
+			}
+			if !slices.Equal(glue, tt.wantGlue) {
+				t.Errorf("glue = %+v, want matches %v", delegation.Glue, tt.wantGlue)
+			}
+
+			if !slices.Equal(delegation.MissingAtChild, tt.missingAtChild) || !slices.Equal(delegation.MissingAtParent, tt.missingAtParent) {
+				t.Errorf("missing at child %v, at parent %v; want %v, %v",
+					delegation.MissingAtChild, delegation.MissingAtParent, tt.missingAtChild, tt.missingAtParent)
+			}
+
+			if len(delegation.Issues) != len(tt.wantIssues) || delegation.Consistent != (len(tt.wantIssues) == 0) {
+				t.Fatalf("consistent = %v, issues = %q; want %q", delegation.Consistent, delegation.Issues, tt.wantIssues)
+			}
+			for i, want := range tt.wantIssues {
+				if !strings.HasPrefix(delegation.Issues[i], want) {
+					t.Errorf("issue %d = %q, want prefix %q", i, delegation.Issues[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// verifyDenial checks that the NSEC or NSEC3 records in ns prove that qname
+// has no records of type qtype, or that it does not exist at all when rcode
+// is NXDOMAIN (RFC 4035 section 5.4, RFC 5155 section 8). The signatures over
+// the records must already have been verified.
+func verifyDenial(qname string, qtype uint16, rcode int, ns []dns.RR) error {
+	var nsecs []*dns.NSEC
+	var nsec3s []*dns.NSEC3
+	for _, rr := range ns {
+		switch record := rr.(type) {
+		case *dns.NSEC:
+			nsecs = append(nsecs, record)
+		case *dns.NSEC3:
+			nsec3s = append(nsec3s, record)
+		}
+	}
+
+	qname = dns.CanonicalName(qname)
+	switch {
+	case len(nsecs) > 0:
+		return denyNSEC(qname, qtype, rcode, nsecs)
+	case len(nsec3s) > 0:
+		return denyNSEC3(qname, qtype, rcode, nsec3s)
+	}
+	return errors.New("no NSEC or NSEC3 records prove the denial")
+}
+
+// denyNSEC checks a denial made with NSEC records
+func denyNSEC(qname string, qtype uint16, rcode int, nsecs []*dns.NSEC) error {
+	if rcode != dns.RcodeNameError {
+		// An NSEC owned by the name lists the types it has
+		for _, nsec := range nsecs {
+			if dns.CanonicalName(nsec.Hdr.Name) == qname {
+				return checkTypeBitmap(nsec.Hdr.Name, nsec.TypeBitMap, qtype)
+			}
+		}
+	}
+
+	var covering *dns.NSEC
+	for _, nsec := range nsecs {
+		if nsecCovers(nsec.Hdr.Name, nsec.NextDomain, qname) {
+			covering = nsec
+			break
+		}
+	}
+	if covering == nil {
+		return fmt.Errorf("no NSEC matches or covers %s", qname)
+	}
+
+	// A covered name whose next name lies below it is an empty non-terminal,
+	// which exists without any records
+	next := dns.CanonicalName(covering.NextDomain)
+	if rcode != dns.RcodeNameError && isStrictParent(qname, next) {
+		return nil
+	}
+
+	// The name could still have been synthesized from a wildcard at its
+	// closest encloser
+	labels := dns.CompareDomainName(qname, covering.Hdr.Name)
+	if n := dns.CompareDomainName(qname, next); n > labels {
+		labels = n
+	}
+	wildcard := wildcardName(ancestor(qname, labels))
+
+	for _, nsec := range nsecs {
+		if dns.CanonicalName(nsec.Hdr.Name) != wildcard {
+			continue
+		}
+		if rcode == dns.RcodeNameError {
+			return fmt.Errorf("wildcard %s exists, so %s cannot be NXDOMAIN", wildcard, qname)
+		}
+		return checkTypeBitmap(nsec.Hdr.Name, nsec.TypeBitMap, qtype)
+	}
+	if rcode != dns.RcodeNameError {
+		return fmt.Errorf("NSEC covering %s denies the name, but the response is not NXDOMAIN", qname)
+	}
+	for _, nsec := range nsecs {
+		if nsecCovers(nsec.Hdr.Name, nsec.NextDomain, wildcard) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no NSEC proves that wildcard %s does not exist", wildcard)
+}
+
+// denyNSEC3 checks a denial made with NSEC3 records
+func denyNSEC3(qname string, qtype uint16, rcode int, nsec3s []*dns.NSEC3) error {
+	if rcode != dns.RcodeNameError {
+		for _, nsec3 := range nsec3s {
+			if nsec3.Match(qname) {
+				return checkTypeBitmap(qname, nsec3.TypeBitMap, qtype)
+			}
+		}
+	}
+
+	closest, nextCloser := nsec3ClosestEncloser(qname, nsec3s)
+	if nextCloser == nil {
+		return fmt.Errorf("no NSEC3 closest encloser proof for %s", qname)
+	}
+	wildcard := wildcardName(closest)
+
+	if rcode == dns.RcodeNameError {
+		for _, nsec3 := range nsec3s {
+			if nsec3.Cover(wildcard) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no NSEC3 proves that wildcard %s does not exist", wildcard)
+	}
+
+	for _, nsec3 := range nsec3s {
+		if nsec3.Match(wildcard) {
+			return checkTypeBitmap(wildcard, nsec3.TypeBitMap, qtype)
+		}
+	}
+	// An opt-out span may hold unsigned delegations, which have no DS
+	// (RFC 5155 section 8.6)
+	if qtype == dns.TypeDS && nextCloser.Flags&1 == 1 {
+		return nil
+	}
+	return fmt.Errorf("no NSEC3 matches %s", qname)
+}
+
+// verifyWildcardExpansion checks that the NSEC or NSEC3 records in ns prove
+// that qname does not exist, so that an answer synthesized from the wildcard
+// below its ancestor with the given label count is legitimate (RFC 4035
+// section 5.3.4, RFC 5155 section 8.8)
+func verifyWildcardExpansion(qname string, labels int, ns []dns.RR) error {
+	qname = dns.CanonicalName(qname)
+	nextCloser := ancestor(qname, labels+1)
+	for _, rr := range ns {
+		switch record := rr.(type) {
+		case *dns.NSEC:
+			if nsecCovers(record.Hdr.Name, record.NextDomain, qname) {
+				return nil
+			}
+		case *dns.NSEC3:
+			if record.Cover(nextCloser) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no NSEC or NSEC3 proves that no name closer than %s exists", wildcardName(ancestor(qname, labels)))
+}
+
+// nsec3ClosestEncloser finds the closest ancestor of qname with a matching
+// NSEC3 and returns it with the NSEC3 covering the next closer name. The
+// NSEC3 is nil when there is no proof.
+func nsec3ClosestEncloser(qname string, nsec3s []*dns.NSEC3) (string, *dns.NSEC3) {
+	count := dns.CountLabel(qname)
+	for labels := count - 1; labels >= 0; labels-- {
+		closest := ancestor(qname, labels)
+		matched := false
+		for _, nsec3 := range nsec3s {
+			if nsec3.Match(closest) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		nextCloser := ancestor(qname, labels+1)
+		for _, nsec3 := range nsec3s {
+			if nsec3.Cover(nextCloser) {
+				return closest, nsec3
+			}
+		}
+		return closest, nil
+	}
+	return "", nil
+}
+
+// checkTypeBitmap fails when the NSEC or NSEC3 type bitmap of owner shows
+// that it has records of qtype, or a CNAME that should have been followed
+func checkTypeBitmap(owner string, bitmap []uint16, qtype uint16) error {
+	for _, t := range bitmap {
+		switch {
+		case t == qtype:
+			return fmt.Errorf("NSEC bitmap of %s lists %s", owner, dns.TypeToString[qtype])
+		case t == dns.TypeCNAME && qtype != dns.TypeCNAME:
+			return fmt.Errorf("NSEC bitmap of %s lists CNAME", owner)
+		}
+	}
+	return nil
+}
+
+// nsecCovers reports whether name sorts strictly between owner and next.
+// The last NSEC of a zone points back to the apex and covers every name
+// after its owner.
+func nsecCovers(owner string, next string, name string) bool {
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(owner, name) < 0 && canonicalCompare(name, next) < 0
+	}
+	return canonicalCompare(owner, name) < 0 && dns.IsSubDomain(next, name)
+}
+
+// canonicalCompare orders names as in RFC 4034 section 6.1, comparing
+// lowercased labels from the rightmost one
+func canonicalCompare(a string, b string) int {
+	la := dns.SplitDomainName(dns.CanonicalName(a))
+	lb := dns.SplitDomainName(dns.CanonicalName(b))
+	for i := 1; i <= len(la) && i <= len(lb); i++ {
+		if c := strings.Compare(la[len(la)-i], lb[len(lb)-i]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// ancestor returns the rightmost labels of name
+func ancestor(name string, labels int) string {
+	all := dns.SplitDomainName(name)
+	if labels <= 0 {
+		return "."
+	}
+	return dns.Fqdn(strings.Join(all[len(all)-labels:], "."))
+}
+
+// wildcardName returns the wildcard directly below name
+func wildcardName(name string) string {
+	if name == "." {
+		return "*."
+	}
+	return "*." + name
+}
@@ -0,0 +1,566 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// rootTrustAnchors holds the DS records of the root zone KSKs (KSK-2017 and KSK-2024)
+const rootTrustAnchors = `
+. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBB683457104237C7F8EC8D
+. IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16
+`
+
+// maxChainDepth bounds the number of zone cuts followed from a trust anchor
+const maxChainDepth = 32
+
+// supportedAlgorithms lists the DNSKEY algorithms that can be verified.
+// Zones signed only with other algorithms are treated as insecure (RFC 4035 section 5.2).
+var supportedAlgorithms = map[uint8]bool{
+	dns.RSASHA1:          true,
+	dns.RSASHA1NSEC3SHA1: true,
+	dns.RSASHA256:        true,
+	dns.RSASHA512:        true,
+	dns.ECDSAP256SHA256:  true,
+	dns.ECDSAP384SHA384:  true,
+	dns.ED25519:          true,
+}
+
+var supportedDigests = map[uint8]bool{
+	dns.SHA1:   true,
+	dns.SHA256: true,
+	dns.SHA384: true,
+}
+
+// TrustAnchors maps a zone name to its DS or DNSKEY anchor records
+type TrustAnchors map[string][]dns.RR
+
+var (
+	trustAnchorsMu     sync.Mutex
+	trustAnchorsByFile = make(map[string]TrustAnchors)
+)
+
+// LoadTrustAnchors reads DS or DNSKEY records in zone file format from path.
+// An empty path returns the built-in root zone anchors.
+func LoadTrustAnchors(path string) (TrustAnchors, error) {
+	trustAnchorsMu.Lock()
+	defer trustAnchorsMu.Unlock()
+
+	if anchors, ok := trustAnchorsByFile[path]; ok {
+		return anchors, nil
+	}
+
+	source := rootTrustAnchors
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trust anchor file: %w", err)
+		}
+		source = string(data)
+	}
+
+	anchors := make(TrustAnchors)
+	parser := dns.NewZoneParser(strings.NewReader(source), ".", path)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			owner := dns.CanonicalName(rr.Header().Name)
+			anchors[owner] = append(anchors[owner], rr)
+		}
+	}
+	if err := parser.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse trust anchors: %w", err)
+	}
+	if len(anchors) == 0 {
+		return nil, errors.New("no DS or DNSKEY records found in trust anchor file")
+	}
+
+	trustAnchorsByFile[path] = anchors
+	return anchors, nil
+}
+
+// zoneKeys is the validated state of a zone's DNSKEY RRset
+type zoneKeys struct {
+	status result.DNSSECStatus
+	reason string
+	keys   []*dns.DNSKEY
+}
+
+// dnssecValidator walks the chain of trust for one query. Zone keys are cached
+// in the session so later queries from the same worker reuse them.
+type dnssecValidator struct {
+	session *Session
+	cfg     config.Config
+	server  string
+	network string
+	anchors TrustAnchors
+	now     time.Time
+}
+
+// validateDNSSEC fetches the RRSIG/DNSKEY/DS records needed to validate the
+// answer to spec through the recursive resolver at server
+func (s *Session) validateDNSSEC(spec QuerySpec, cfg config.Config, server string) *result.DNSSECResult {
+	anchors, err := LoadTrustAnchors(cfg.TrustAnchorFile)
+	if err != nil {
+		return indeterminate("", err.Error())
+	}
+
+	v := &dnssecValidator{
+		session: s,
+		cfg:     cfg,
+		server:  server,
+		network: TCP.Network(spec.IPVersion),
+		anchors: anchors,
+		now:     time.Now(),
+	}
+
+	return v.validate(dns.Fqdn(spec.Domain), uint16(spec.QueryType))
+}
+
+func (v *dnssecValidator) validate(qname string, qtype uint16) *result.DNSSECResult {
+	resp, err := v.query(qname, qtype)
+	if err != nil {
+		return indeterminate("", fmt.Sprintf("lookup failed: %v", err))
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return indeterminate("", fmt.Sprintf("resolver returned %s", dns.RcodeToString[resp.Rcode]))
+	}
+
+	// When the answer holds no records of the queried type, whether it is
+	// empty or a CNAME chain, the signed SOA and NSEC/NSEC3 records in the
+	// authority section must prove the denial for the end of the chain
+	chain := &result.CNAMEChain{Final: qname}
+	extendChain(chain, resp.Answer, "", maxChainDepth)
+	denied := len(resp.Answer) == 0 || resp.Rcode == dns.RcodeNameError
+	if qtype != dns.TypeCNAME && qtype != dns.TypeANY && len(chainRecords(resp.Answer, chain.Final, qtype)) == 0 {
+		denied = true
+	}
+
+	// An answer synthesized from a wildcard must come with the NSEC or NSEC3
+	// records proving that the queried name itself does not exist
+	expanded := wildcardExpansions(resp.Answer)
+	section := resp.Answer
+	switch {
+	case denied:
+		section = append(append([]dns.RR(nil), resp.Answer...), resp.Ns...)
+	case len(expanded) > 0:
+		section = append(append([]dns.RR(nil), resp.Answer...), denialRecords(resp.Ns)...)
+	}
+	rrsets, sigs := groupRRsets(section)
+	if len(rrsets) == 0 {
+		return indeterminate("", "response contained no records to validate")
+	}
+
+	var signer string
+	for _, rrset := range rrsets {
+		header := rrset[0].Header()
+		covering := coveringSignatures(sigs, header.Name, header.Rrtype)
+
+		zone, err := v.findZone(header.Name)
+		if err != nil {
+			return indeterminate("", err.Error())
+		}
+		if len(covering) > 0 {
+			// The signer must be the zone holding the RRset, or a forged
+			// signer naming an unsigned zone would pass as insecure
+			signer := dns.CanonicalName(covering[0].SignerName)
+			if !dns.IsSubDomain(signer, header.Name) {
+				return bogus(zone, fmt.Sprintf("RRSIG signer %s is not an ancestor of %s", signer, header.Name))
+			}
+			if signer != zone {
+				if zone, err = v.parentSideZone(zone, header.Name, header.Rrtype); err != nil {
+					return indeterminate("", err.Error())
+				}
+				if signer != zone {
+					return bogus(zone, fmt.Sprintf("RRSIG signer %s of %s %s does not match its zone %s",
+						signer, header.Name, dns.TypeToString[header.Rrtype], zone))
+				}
+			}
+		}
+
+		zk := v.zone(zone, 0)
+		if zk.status != result.DNSSECSecure {
+			return &result.DNSSECResult{Status: zk.status, Reason: zk.reason, Zone: zone}
+		}
+
+		if len(covering) == 0 {
+			return bogus(zone, fmt.Sprintf("missing RRSIG for %s %s in signed zone", header.Name, dns.TypeToString[header.Rrtype]))
+		}
+		if err := v.verifyRRset(rrset, covering, zk.keys); err != nil {
+			return bogus(zone, fmt.Sprintf("%s %s: %v", header.Name, dns.TypeToString[header.Rrtype], err))
+		}
+		signer = zone
+	}
+
+	for owner, labels := range expanded {
+		if err := verifyWildcardExpansion(owner, labels, resp.Ns); err != nil {
+			return bogus(signer, fmt.Sprintf("wildcard answer for %s: %v", owner, err))
+		}
+	}
+
+	if denied {
+		if err := verifyDenial(chain.Final, qtype, resp.Rcode, resp.Ns); err != nil {
+			return bogus(signer, fmt.Sprintf("denial of %s %s: %v", chain.Final, dns.TypeToString[qtype], err))
+		}
+	}
+
+	return &result.DNSSECResult{Status: result.DNSSECSecure, Zone: signer}
+}
+
+// zone returns the validated DNSKEY set of a zone apex, following DS records
+// up to the closest trust anchor
+func (v *dnssecValidator) zone(name string, depth int) *zoneKeys {
+	name = dns.CanonicalName(name)
+	cacheKey := v.server + "|" + name
+	if zk, ok := v.session.dnssecZones[cacheKey]; ok {
+		return zk
+	}
+
+	zk := v.resolveZone(name, depth)
+	v.session.dnssecZones[cacheKey] = zk
+	return zk
+}
+
+func (v *dnssecValidator) resolveZone(name string, depth int) *zoneKeys {
+	if depth > maxChainDepth {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: "chain of trust too long"}
+	}
+
+	// A configured anchor ends the walk
+	if anchor, ok := v.anchors[name]; ok {
+		return v.zoneFromAnchor(name, anchor)
+	}
+	if name == "." {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: "no trust anchor covers this name"}
+	}
+
+	// The DS RRset lives in (and is signed by) the parent zone
+	resp, err := v.query(name, dns.TypeDS)
+	if err != nil {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: fmt.Sprintf("DS lookup for %s failed: %v", name, err)}
+	}
+
+	dsSet, sigs := extractType(resp.Answer, dns.TypeDS)
+	if len(dsSet) == 0 {
+		return v.insecureDelegation(name, resp, depth)
+	}
+
+	if len(sigs) == 0 {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("DS for %s is not signed", name)}
+	}
+	parent := dns.CanonicalName(sigs[0].SignerName)
+	if !isStrictParent(parent, name) {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: fmt.Sprintf("unexpected DS signer %s for %s", parent, name)}
+	}
+
+	parentKeys := v.zone(parent, depth+1)
+	if parentKeys.status != result.DNSSECSecure {
+		return parentKeys
+	}
+	if err := v.verifyRRset(dsSet, sigs, parentKeys.keys); err != nil {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("DS for %s: %v", name, err)}
+	}
+
+	return v.zoneFromAnchor(name, dsSet)
+}
+
+// insecureDelegation handles a DS lookup without DS records. The denial must be
+// signed by the parent zone and its NSEC or NSEC3 records must prove that the
+// DS RRset does not exist for the child to be provably insecure.
+func (v *dnssecValidator) insecureDelegation(name string, resp *dns.Msg, depth int) *zoneKeys {
+	parent := ""
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			parent = dns.CanonicalName(soa.Hdr.Name)
+		}
+	}
+	if parent == "" || !isStrictParent(parent, name) {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: fmt.Sprintf("cannot locate parent zone of %s", name)}
+	}
+
+	parentKeys := v.zone(parent, depth+1)
+	if parentKeys.status != result.DNSSECSecure {
+		return parentKeys
+	}
+
+	rrsets, sigs := groupRRsets(resp.Ns)
+	for _, rrset := range rrsets {
+		header := rrset[0].Header()
+		if err := v.verifyRRset(rrset, coveringSignatures(sigs, header.Name, header.Rrtype), parentKeys.keys); err != nil {
+			return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("missing DS for %s and its denial failed validation: %v", name, err)}
+		}
+	}
+	if err := verifyDenial(name, dns.TypeDS, resp.Rcode, resp.Ns); err != nil {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("missing DS for %s is not proven: %v", name, err)}
+	}
+
+	return &zoneKeys{status: result.DNSSECInsecure, reason: fmt.Sprintf("missing DS for %s: unsigned delegation", name)}
+}
+
+// zoneFromAnchor fetches the zone's DNSKEY RRset and accepts it if it is signed
+// by a key matching one of the DS or DNSKEY records in anchor
+func (v *dnssecValidator) zoneFromAnchor(name string, anchor []dns.RR) *zoneKeys {
+	usable := 0
+	for _, rr := range anchor {
+		switch a := rr.(type) {
+		case *dns.DS:
+			if supportedAlgorithms[a.Algorithm] && supportedDigests[a.DigestType] {
+				usable++
+			}
+		case *dns.DNSKEY:
+			if supportedAlgorithms[a.Algorithm] {
+				usable++
+			}
+		}
+	}
+	if usable == 0 {
+		return &zoneKeys{status: result.DNSSECInsecure, reason: fmt.Sprintf("algorithm unsupported for %s", name)}
+	}
+
+	resp, err := v.query(name, dns.TypeDNSKEY)
+	if err != nil {
+		return &zoneKeys{status: result.DNSSECIndeterminate, reason: fmt.Sprintf("DNSKEY lookup for %s failed: %v", name, err)}
+	}
+
+	keySet, sigs := extractType(resp.Answer, dns.TypeDNSKEY)
+	if len(keySet) == 0 {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("no DNSKEY records for %s", name)}
+	}
+
+	var keys []*dns.DNSKEY
+	for _, rr := range keySet {
+		keys = append(keys, rr.(*dns.DNSKEY))
+	}
+
+	// Keep only keys that match the anchor, then require one of them to sign the set
+	var trusted []*dns.DNSKEY
+	for _, key := range keys {
+		if matchesAnchor(key, anchor) {
+			trusted = append(trusted, key)
+		}
+	}
+	if len(trusted) == 0 {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("no DNSKEY for %s matches its DS records", name)}
+	}
+	if err := v.verifyRRset(keySet, sigs, trusted); err != nil {
+		return &zoneKeys{status: result.DNSSECBogus, reason: fmt.Sprintf("DNSKEY for %s: %v", name, err)}
+	}
+
+	return &zoneKeys{status: result.DNSSECSecure, keys: keys}
+}
+
+// findZone locates the zone apex containing name using an SOA query
+func (v *dnssecValidator) findZone(name string) (string, error) {
+	resp, err := v.query(name, dns.TypeSOA)
+	if err != nil {
+		return "", fmt.Errorf("SOA lookup for %s failed: %v", name, err)
+	}
+
+	for _, rr := range append(resp.Answer, resp.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return dns.CanonicalName(soa.Hdr.Name), nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot locate zone of %s", name)
+}
+
+// parentSideZone returns the zone that signs rrtype at owner when owner is
+// the apex of zone. DS records, and the NSEC records denying them, live on
+// the parent side of the zone cut; every other RRset belongs to zone itself.
+func (v *dnssecValidator) parentSideZone(zone string, owner string, rrtype uint16) (string, error) {
+	if zone != dns.CanonicalName(owner) || zone == "." || (rrtype != dns.TypeDS && rrtype != dns.TypeNSEC) {
+		return zone, nil
+	}
+	return v.findZone(ancestor(zone, dns.CountLabel(zone)-1))
+}
+
+// verifyRRset checks that at least one currently valid signature over rrset
+// was made by one of keys
+func (v *dnssecValidator) verifyRRset(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) error {
+	if len(sigs) == 0 {
+		return errors.New("missing RRSIG")
+	}
+
+	lastErr := errors.New("no DNSKEY matches RRSIG key tag")
+	for _, sig := range sigs {
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if !supportedAlgorithms[sig.Algorithm] {
+				lastErr = fmt.Errorf("algorithm unsupported (%s)", dns.AlgorithmToString[sig.Algorithm])
+				continue
+			}
+			if !sig.ValidityPeriod(v.now) {
+				lastErr = signatureTimeError(sig, v.now)
+				continue
+			}
+			if err := sig.Verify(key, rrset); err != nil {
+				lastErr = fmt.Errorf("signature verification failed: %v", err)
+				continue
+			}
+			return nil
+		}
+	}
+
+	return lastErr
+}
+
+// query sends a DO+CD query to the resolver so it returns DNSSEC records without
+// filtering out data that fails its own validation
+func (v *dnssecValidator) query(name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+	msg.RecursionDesired = true
+	msg.CheckingDisabled = true
+	msg.SetEdns0(dns.DefaultMsgSize, true)
+
+	client := &dns.Client{
+		Net:     v.network,
+		Timeout: v.cfg.Timeout,
+	}
+
+	resp, _, err := client.Exchange(msg, v.server)
+	return resp, err
+}
+
+func signatureTimeError(sig *dns.RRSIG, now time.Time) error {
+	inception := time.Unix(int64(sig.Inception), 0).UTC()
+	expiration := time.Unix(int64(sig.Expiration), 0).UTC()
+	if now.Before(inception) {
+		return fmt.Errorf("signature not yet valid (inception %s)", inception.Format(time.RFC3339))
+	}
+	return fmt.Errorf("signature expired (expiration %s)", expiration.Format(time.RFC3339))
+}
+
+// matchesAnchor reports whether key is one of the anchor DNSKEYs or hashes to one of its DS records
+func matchesAnchor(key *dns.DNSKEY, anchor []dns.RR) bool {
+	for _, rr := range anchor {
+		switch a := rr.(type) {
+		case *dns.DS:
+			if a.KeyTag != key.KeyTag() || a.Algorithm != key.Algorithm || !supportedDigests[a.DigestType] {
+				continue
+			}
+			if ds := key.ToDS(a.DigestType); ds != nil && strings.EqualFold(ds.Digest, a.Digest) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if a.PublicKey == key.PublicKey && a.Algorithm == key.Algorithm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupRRsets splits a section into RRsets keyed by owner and type, separating out the RRSIGs
+func groupRRsets(rrs []dns.RR) ([][]dns.RR, []*dns.RRSIG) {
+	var rrsets [][]dns.RR
+	var sigs []*dns.RRSIG
+	index := make(map[string]int)
+
+	for _, rr := range rrs {
+		switch r := rr.(type) {
+		case *dns.RRSIG:
+			sigs = append(sigs, r)
+			continue
+		case *dns.OPT:
+			continue
+		}
+
+		key := dns.CanonicalName(rr.Header().Name) + "/" + dns.TypeToString[rr.Header().Rrtype]
+		if i, ok := index[key]; ok {
+			rrsets[i] = append(rrsets[i], rr)
+			continue
+		}
+		index[key] = len(rrsets)
+		rrsets = append(rrsets, []dns.RR{rr})
+	}
+
+	return rrsets, sigs
+}
+
+// extractType returns the records of one type and the signatures covering them
+func extractType(rrs []dns.RR, rrtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var set []dns.RR
+	var sigs []*dns.RRSIG
+
+	for _, rr := range rrs {
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == rrtype {
+			sigs = append(sigs, sig)
+		} else if rr.Header().Rrtype == rrtype {
+			set = append(set, rr)
+		}
+	}
+
+	return set, sigs
+}
+
+func coveringSignatures(sigs []*dns.RRSIG, owner string, rrtype uint16) []*dns.RRSIG {
+	var covering []*dns.RRSIG
+	for _, sig := range sigs {
+		if sig.TypeCovered == rrtype && strings.EqualFold(sig.Hdr.Name, owner) {
+			covering = append(covering, sig)
+		}
+	}
+	return covering
+}
+
+// wildcardExpansions maps the owner of every answer RRset whose RRSIG has
+// fewer labels than the owner, meaning it was synthesized from a wildcard,
+// to the label count of the wildcard's parent (RFC 4035 section 5.3.2)
+func wildcardExpansions(answer []dns.RR) map[string]int {
+	expanded := make(map[string]int)
+	for _, rr := range answer {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		owner := dns.CanonicalName(sig.Hdr.Name)
+		labels := dns.CountLabel(owner)
+		if strings.HasPrefix(owner, "*.") {
+			labels-- // The wildcard owner itself, not an expansion
+		}
+		if int(sig.Labels) < labels {
+			expanded[owner] = int(sig.Labels)
+		}
+	}
+	return expanded
+}
+
+// denialRecords returns the NSEC and NSEC3 records of a section with their signatures
+func denialRecords(rrs []dns.RR) []dns.RR {
+	var records []dns.RR
+	for _, rr := range rrs {
+		rrtype := rr.Header().Rrtype
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			rrtype = sig.TypeCovered
+		}
+		if rrtype == dns.TypeNSEC || rrtype == dns.TypeNSEC3 {
+			records = append(records, rr)
+		}
+	}
+	return records
+}
+
+// isStrictParent reports whether parent is a proper ancestor of child
+func isStrictParent(parent string, child string) bool {
+	return parent != child && dns.IsSubDomain(parent, child)
+}
+
+func indeterminate(zone string, reason string) *result.DNSSECResult {
+	return &result.DNSSECResult{Status: result.DNSSECIndeterminate, Reason: reason, Zone: zone}
+}
+
+func bogus(zone string, reason string) *result.DNSSECResult {
+	return &result.DNSSECResult{Status: result.DNSSECBogus, Reason: reason, Zone: zone}
+}
@@ -0,0 +1,317 @@
+package query
+
+import (
+	"crypto"
+	"dns_query_utility/result"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// signedZone signs records for a test zone with a single ECDSA key
+type signedZone struct {
+	origin string
+	key    *dns.DNSKEY
+	signer crypto.Signer
+}
+
+func newSignedZone(t *testing.T, origin string) *signedZone {
+	t.Helper()
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return &signedZone{origin: origin, key: key, signer: priv.(crypto.Signer)}
+}
+
+// rrs parses records in zone file format and appends an RRSIG per RRset
+func (z *signedZone) rrs(t *testing.T, lines ...string) []dns.RR {
+	t.Helper()
+	return z.rrsWith(t, func(*dns.RRSIG) {}, lines...)
+}
+
+// rrsWith is rrs with a hook that adjusts each RRSIG before it is signed
+func (z *signedZone) rrsWith(t *testing.T, adjust func(*dns.RRSIG), lines ...string) []dns.RR {
+	t.Helper()
+
+	var records []dns.RR
+	for _, line := range lines {
+		rr, err := dns.NewRR(line)
+		if err != nil {
+			t.Fatalf("parse %q: %v", line, err)
+		}
+		records = append(records, rr)
+	}
+
+	rrsets, _ := groupRRsets(records)
+	for _, rrset := range rrsets {
+		records = append(records, z.signWith(t, rrset, adjust))
+	}
+	return records
+}
+
+func (z *signedZone) sign(t *testing.T, rrset []dns.RR) *dns.RRSIG {
+	t.Helper()
+	return z.signWith(t, rrset, func(*dns.RRSIG) {})
+}
+
+func (z *signedZone) signWith(t *testing.T, rrset []dns.RR, adjust func(*dns.RRSIG)) *dns.RRSIG {
+	t.Helper()
+
+	now := time.Now()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Ttl: rrset[0].Header().Ttl},
+		Algorithm:  z.key.Algorithm,
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.origin,
+		Inception:  uint32(now.Add(-time.Hour).Unix()),
+		Expiration: uint32(now.Add(time.Hour).Unix()),
+	}
+	adjust(sig)
+	if err := sig.Sign(z.signer, rrset); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return sig
+}
+
+// anchorFile writes the zone's DNSKEY as a trust anchor
+func (z *signedZone) anchorFile(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "anchors.zone")
+	if err := os.WriteFile(path, []byte(z.key.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("write anchors: %v", err)
+	}
+	return path
+}
+
+// wildcardAnswer signs "<qname> 300 IN A 192.0.2.1" as an expansion of the
+// wildcard *.example.
+func (z *signedZone) wildcardAnswer(t *testing.T, qname string) []dns.RR {
+	t.Helper()
+
+	rr := mustRR(t, "*.example. 300 IN A 192.0.2.1")
+	sig := z.sign(t, []dns.RR{rr})
+	rr.Header().Name = qname
+	sig.Hdr.Name = qname
+	return []dns.RR{rr, sig}
+}
+
+func TestValidateDNSSEC(t *testing.T) {
+	zone := newSignedZone(t, "example.")
+	keys := append([]dns.RR{zone.key}, zone.sign(t, []dns.RR{zone.key}))
+
+	soa := "example. 300 IN SOA ns.example. admin.example. 1 7200 3600 1209600 300"
+	unsignedSub := newSignedZone(t, "sub.example.")
+	hoursAgo := func(from, to int) func(*dns.RRSIG) {
+		return func(sig *dns.RRSIG) {
+			sig.Inception = uint32(time.Now().Add(-time.Duration(from) * time.Hour).Unix())
+			sig.Expiration = uint32(time.Now().Add(-time.Duration(to) * time.Hour).Unix())
+		}
+	}
+	nsec3Owner := func(name string) string {
+		return strings.ToLower(dns.HashName(name, dns.SHA1, 0, "")) + ".example."
+	}
+
+	tests := []struct {
+		name   string
+		qname  string
+		qtype  QueryType
+		rcode  int
+		ns     []dns.RR
+		dsNs   []dns.RR // Authority section for the DS query of sub.example.
+		answer []dns.RR
+		want   result.DNSSECStatus
+	}{
+		{
+			name:  "signed answer",
+			qname: "www.example", qtype: QueryTypeA,
+			answer: zone.rrs(t, "www.example. 300 IN A 192.0.2.1"),
+			want:   result.DNSSECSecure,
+		},
+		{
+			name:  "expired RRSIG",
+			qname: "www.example", qtype: QueryTypeA,
+			answer: zone.rrsWith(t, hoursAgo(48, 24), "www.example. 300 IN A 192.0.2.1"),
+			want:   result.DNSSECBogus,
+		},
+		{
+			name:  "RRSIG not yet valid",
+			qname: "www.example", qtype: QueryTypeA,
+			answer: zone.rrsWith(t, hoursAgo(-24, -48), "www.example. 300 IN A 192.0.2.1"),
+			want:   result.DNSSECBogus,
+		},
+		{
+			// sub.example. is provably unsigned, so trusting the signer
+			// would report the forged answer as insecure
+			name:  "RRSIG signer not an ancestor of the owner",
+			qname: "www.example", qtype: QueryTypeA,
+			answer: unsignedSub.rrs(t, "www.example. 300 IN A 192.0.2.1"),
+			dsNs:   zone.rrs(t, soa, "sub.example. 300 IN NSEC www.example. NS RRSIG NSEC"),
+			want:   result.DNSSECBogus,
+		},
+		{
+			name:  "RRSIG signed by the parent of the owner's zone",
+			qname: "www.sub.example", qtype: QueryTypeA,
+			answer: zone.rrs(t, "www.sub.example. 300 IN A 192.0.2.1"),
+			dsNs:   zone.rrs(t, soa, "sub.example. 300 IN NSEC www.example. NS RRSIG NSEC"),
+			want:   result.DNSSECBogus,
+		},
+		{
+			name:  "wildcard answer with NSEC proof",
+			qname: "mail.example", qtype: QueryTypeA,
+			answer: zone.wildcardAnswer(t, "mail.example."),
+			ns:     zone.rrs(t, "a.example. 300 IN NSEC www.example. A RRSIG NSEC"),
+			want:   result.DNSSECSecure,
+		},
+		{
+			name:  "wildcard answer with NSEC3 proof",
+			qname: "mail.example", qtype: QueryTypeA,
+			answer: zone.wildcardAnswer(t, "mail.example."),
+			ns:     zone.rrs(t, strings.Repeat("0", 32)+".example. 300 IN NSEC3 1 0 0 - "+strings.Repeat("V", 32)+" A RRSIG"),
+			want:   result.DNSSECSecure,
+		},
+		{
+			name:  "wildcard answer without proof",
+			qname: "mail.example", qtype: QueryTypeA,
+			answer: zone.wildcardAnswer(t, "mail.example."),
+			want:   result.DNSSECBogus,
+		},
+		{
+			name:  "NODATA proven by NSEC",
+			qname: "www.example", qtype: QueryTypeTXT,
+			ns:   zone.rrs(t, soa, "www.example. 300 IN NSEC zz.example. A RRSIG NSEC"),
+			want: result.DNSSECSecure,
+		},
+		{
+			name:  "NODATA without NSEC",
+			qname: "www.example", qtype: QueryTypeTXT,
+			ns:   zone.rrs(t, soa),
+			want: result.DNSSECBogus,
+		},
+		{
+			name:  "NODATA with the type in the bitmap",
+			qname: "www.example", qtype: QueryTypeTXT,
+			ns:   zone.rrs(t, soa, "www.example. 300 IN NSEC zz.example. A TXT RRSIG NSEC"),
+			want: result.DNSSECBogus,
+		},
+		{
+			name:  "NODATA for an empty non-terminal",
+			qname: "b.example", qtype: QueryTypeA,
+			ns:   zone.rrs(t, soa, "a.example. 300 IN NSEC www.b.example. A RRSIG NSEC"),
+			want: result.DNSSECSecure,
+		},
+		{
+			name:  "NXDOMAIN proven by NSEC",
+			qname: "mail.example", qtype: QueryTypeA, rcode: dns.RcodeNameError,
+			ns: zone.rrs(t, soa,
+				"example. 300 IN NSEC a.example. SOA NS RRSIG NSEC DNSKEY",
+				"a.example. 300 IN NSEC www.example. A RRSIG NSEC"),
+			want: result.DNSSECSecure,
+		},
+		{
+			name:  "NXDOMAIN without a wildcard proof",
+			qname: "mail.example", qtype: QueryTypeA, rcode: dns.RcodeNameError,
+			ns:   zone.rrs(t, soa, "a.example. 300 IN NSEC www.example. A RRSIG NSEC"),
+			want: result.DNSSECBogus,
+		},
+		{
+			name:  "NXDOMAIN with a non-covering NSEC",
+			qname: "mail.example", qtype: QueryTypeA, rcode: dns.RcodeNameError,
+			ns:   zone.rrs(t, soa, "example. 300 IN NSEC a.example. SOA NS RRSIG NSEC DNSKEY"),
+			want: result.DNSSECBogus,
+		},
+		{
+			name:  "NODATA proven by NSEC3",
+			qname: "www.example", qtype: QueryTypeTXT,
+			ns:   zone.rrs(t, soa, nsec3Owner("www.example.")+" 300 IN NSEC3 1 0 0 - "+strings.Repeat("V", 32)+" A RRSIG"),
+			want: result.DNSSECSecure,
+		},
+		{
+			name:  "unsigned delegation proven by NSEC",
+			qname: "www.sub.example", qtype: QueryTypeA,
+			answer: []dns.RR{mustRR(t, "www.sub.example. 300 IN A 192.0.2.1")},
+			dsNs:   zone.rrs(t, soa, "sub.example. 300 IN NSEC www.example. NS RRSIG NSEC"),
+			want:   result.DNSSECInsecure,
+		},
+		{
+			name:  "unsigned delegation without proof",
+			qname: "www.sub.example", qtype: QueryTypeA,
+			answer: []dns.RR{mustRR(t, "www.sub.example. 300 IN A 192.0.2.1")},
+			dsNs:   zone.rrs(t, soa),
+			want:   result.DNSSECBogus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				q := r.Question[0]
+				switch {
+				case q.Qtype == dns.TypeDNSKEY && q.Name == "example.":
+					m.Answer = keys
+				case q.Qtype == dns.TypeDS && q.Name == "sub.example.":
+					m.Ns = tt.dsNs
+				case q.Qtype == dns.TypeSOA && dns.IsSubDomain("sub.example.", q.Name):
+					m.Ns = []dns.RR{mustRR(t, "sub.example. 300 IN SOA ns.sub.example. admin.sub.example. 1 7200 3600 1209600 300")}
+				case q.Qtype == dns.TypeSOA:
+					m.Ns = []dns.RR{mustRR(t, soa)}
+				default:
+					m.Rcode = tt.rcode
+					m.Answer = tt.answer
+					m.Ns = tt.ns
+				}
+				w.WriteMsg(m)
+			})
+
+			cfg := testConfig(port)
+			cfg.DNSSECValidate = true
+			cfg.TrustAnchorFile = zone.anchorFile(t)
+
+			spec := QuerySpec{Domain: tt.qname, QueryType: tt.qtype, Transport: UDP, IPVersion: IPv4}
+			res := ExecuteQuery(spec, cfg)
+			if res.DNSSEC == nil {
+				t.Fatalf("no DNSSEC result (error: %s)", res.Error)
+			}
+			if res.DNSSEC.Status != tt.want {
+				t.Errorf("status = %s (%s), want %s", res.DNSSEC.Status, res.DNSSEC.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalCompare(t *testing.T) {
+	// RFC 4034 section 6.1 example order
+	ordered := []string{
+		"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.",
+		"zABC.a.EXAMPLE.", "z.example.", "*.z.example.",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		if canonicalCompare(ordered[i], ordered[i+1]) >= 0 {
+			t.Errorf("%s should sort before %s", ordered[i], ordered[i+1])
+		}
+	}
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return rr
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"dns_query_utility/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const dohMediaType = "application/dns-message"
+
+var (
+	dohClientsMu sync.Mutex
+	dohClients   = make(map[string]*http.Client)
+)
+
+// exchangeDoH sends msg to the configured DoH URL template (RFC 8484) using
+// POST or GET and returns the unpacked response
+func exchangeDoH(msg *dns.Msg, spec QuerySpec, cfg config.Config) (*dns.Msg, error) {
+	if cfg.DoHURL == "" {
+		return nil, errors.New("no DoH URL configured (pass an https:// URL in --dns)")
+	}
+
+	// RFC 8484 section 4.1 recommends ID 0 so responses are cache friendly
+	query := msg.Copy()
+	query.Id = 0
+
+	packed, requestMAC, err := packQuery(query, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack DoH query: %w", err)
+	}
+
+	endpoint := strings.Replace(cfg.DoHURL, "{?dns}", "", 1)
+
+	var req *http.Request
+	if cfg.DoHMethod == "get" {
+		separator := "?"
+		if strings.Contains(endpoint, "?") {
+			separator = "&"
+		}
+		endpoint += separator + "dns=" + base64.RawURLEncoding.EncodeToString(packed)
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	} else {
+		req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(packed))
+		if err == nil {
+			req.Header.Set("Content-Type", dohMediaType)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DoH request: %w", err)
+	}
+	req.Header.Set("Accept", dohMediaType)
+
+	client, err := dohClient(spec, cfg, req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned HTTP %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, dohMediaType) {
+		return nil, fmt.Errorf("unexpected DoH content type '%s'", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+
+	response, err := unpackResponse(body, requestMAC, cfg)
+	if response == nil {
+		return nil, fmt.Errorf("failed to unpack DoH response: %w", err)
+	}
+	response.Id = msg.Id
+
+	return response, err
+}
+
+// dohClient returns a shared HTTP client for the query's IP family so that
+// connections to the DoH server are reused across queries
+func dohClient(spec QuerySpec, cfg config.Config, endpoint *url.URL) (*http.Client, error) {
+	network := spec.Transport.Network(spec.IPVersion)
+
+	dohClientsMu.Lock()
+	defer dohClientsMu.Unlock()
+
+	if client, ok := dohClients[network]; ok {
+		return client, nil
+	}
+
+	tlsOptions := cfg.TLSIPv4
+	if spec.IPVersion == IPv6 {
+		tlsOptions = cfg.TLSIPv6
+	}
+	// The certificate name always comes from the URL; only the pin is shared with DoT
+	tlsConfig, err := newTLSConfig(config.TLSOptions{SPKIPin: tlsOptions.SPKIPin}, endpoint.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSClientConfig:     tlsConfig,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: cfg.WorkerCount,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	dohClients[network] = client
+
+	return client, nil
+}
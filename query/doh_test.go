@@ -0,0 +1,73 @@
+package query
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestExecuteQueryDoH(t *testing.T) {
+	var methods []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var packed []byte
+		var err error
+		if r.Method == http.MethodGet {
+			packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		} else {
+			packed, err = io.ReadAll(r.Body)
+		}
+		query := new(dns.Msg)
+		if err != nil || query.Unpack(packed) != nil {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		methods = append(methods, r.Method)
+
+		m := new(dns.Msg)
+		m.SetReply(query)
+		if query.Id == 0 {
+			rr, _ := dns.NewRR(query.Question[0].Name + " 60 IN A 192.0.2.1")
+			m.Answer = []dns.RR{rr}
+		}
+		reply, _ := m.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		w.Write(reply)
+	}))
+	cert := newTestCert(t, "doh")
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"post", http.MethodPost},
+		{"get", http.MethodGet},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			methods = nil
+
+			cfg := testConfig(0)
+			cfg.DoHURL = server.URL + "/dns-query{?dns}"
+			cfg.DoHMethod = tt.method
+			cfg.TLSIPv4.SPKIPin = spkiPin(t, cert.Certificate[0])
+
+			spec := QuerySpec{Domain: "example.com", QueryType: QueryTypeA, Transport: DoH, IPVersion: IPv4}
+			res := ExecuteQuery(spec, cfg)
+			if len(res.ResolvedIPs) != 1 || res.ResolvedIPs[0] != "192.0.2.1" {
+				t.Errorf("resolved %v (status %s, error %s), want [192.0.2.1]", res.ResolvedIPs, res.Status, res.Error)
+			}
+			if len(methods) != 1 || methods[0] != tt.want {
+				t.Errorf("server saw %v, want one %s request", methods, tt.want)
+			}
+		})
+	}
+}
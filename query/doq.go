@@ -0,0 +1,172 @@
+package query
+
+import (
+	"context"
+	"crypto/tls"
+	"dns_query_utility/config"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqALPN is the ALPN token for DNS-over-QUIC (RFC 9250 section 4.1.1)
+const doqALPN = "doq"
+
+// doqSessionCache is shared by all workers so a reconnecting worker can resume
+// a TLS session obtained by any other worker and send its query as 0-RTT data
+var doqSessionCache = tls.NewLRUClientSessionCache(64)
+
+type doqConn struct {
+	conn      *quic.Conn
+	transport *quic.Transport
+	udpConn   *net.UDPConn
+}
+
+func (dc *doqConn) close() {
+	// DOQ_NO_ERROR (0x0) signals a graceful shutdown
+	dc.conn.CloseWithError(0, "")
+	dc.transport.Close()
+	dc.udpConn.Close()
+}
+
+// exchangeDoQ sends msg on a new stream of the session's connection to server,
+// dialing a connection first if none is open. Each query uses its own
+// bidirectional stream as required by RFC 9250 section 4.2.
+func (s *Session) exchangeDoQ(msg *dns.Msg, server string, spec QuerySpec, cfg config.Config, tlsOptions config.TLSOptions) (*dns.Msg, quic.ConnectionState, error) {
+	dc, err := s.doqConnFor(server, spec, cfg, tlsOptions)
+	if err != nil {
+		return nil, quic.ConnectionState{}, err
+	}
+
+	response, err := exchangeDoQStream(dc.conn, msg, cfg)
+	if isTSIGError(err) {
+		// The exchange itself worked; let the caller report the bad signature
+		return response, dc.conn.ConnectionState(), err
+	}
+	if err != nil {
+		// Drop the connection so the next attempt dials a fresh one
+		dc.close()
+		delete(s.doqConns, server)
+		return nil, quic.ConnectionState{}, err
+	}
+
+	return response, dc.conn.ConnectionState(), nil
+}
+
+func (s *Session) doqConnFor(server string, spec QuerySpec, cfg config.Config, tlsOptions config.TLSOptions) (*doqConn, error) {
+	if dc, ok := s.doqConns[server]; ok {
+		select {
+		case <-dc.conn.Context().Done():
+			dc.close()
+			delete(s.doqConns, server)
+		default:
+			return dc, nil
+		}
+	}
+
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(tlsOptions, host)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.NextProtos = []string{doqALPN}
+	tlsConfig.ClientSessionCache = doqSessionCache
+
+	network := "udp4"
+	if spec.IPVersion == IPv6 {
+		network = "udp6"
+	}
+
+	addr, err := net.ResolveUDPAddr(network, server)
+	if err != nil {
+		return nil, err
+	}
+
+	udpConn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+	transport := &quic.Transport{Conn: udpConn}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	conn, err := transport.DialEarly(ctx, addr, tlsConfig, &quic.Config{
+		HandshakeIdleTimeout: cfg.Timeout,
+		MaxIdleTimeout:       30 * time.Second,
+	})
+	if err != nil {
+		transport.Close()
+		udpConn.Close()
+		return nil, err
+	}
+
+	dc := &doqConn{conn: conn, transport: transport, udpConn: udpConn}
+	s.doqConns[server] = dc
+
+	return dc, nil
+}
+
+// exchangeDoQStream writes one length-prefixed query on a new stream, closes the
+// sending side to signal the end of the query and reads the response
+func exchangeDoQStream(conn *quic.Conn, msg *dns.Msg, cfg config.Config) (*dns.Msg, error) {
+	timeout := cfg.Timeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DoQ stream: %w", err)
+	}
+
+	if err := stream.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	// RFC 9250 section 4.2.1: the message ID MUST be set to 0
+	query := msg.Copy()
+	query.Id = 0
+
+	packed, requestMAC, err := packQuery(query, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack DoQ query: %w", err)
+	}
+
+	frame := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(frame, uint16(len(packed)))
+	copy(frame[2:], packed)
+
+	if _, err := stream.Write(frame); err != nil {
+		return nil, fmt.Errorf("failed to write DoQ query: %w", err)
+	}
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return nil, fmt.Errorf("failed to read DoQ response: %w", err)
+	}
+
+	body := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(stream, body); err != nil {
+		return nil, fmt.Errorf("failed to read DoQ response: %w", err)
+	}
+
+	response, err := unpackResponse(body, requestMAC, cfg)
+	if response == nil {
+		return nil, fmt.Errorf("failed to unpack DoQ response: %w", err)
+	}
+	response.Id = msg.Id
+
+	return response, err
+}
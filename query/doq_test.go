@@ -0,0 +1,114 @@
+package query
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// startDoQServer answers every DoQ stream with an A record and counts the
+// connections it accepted
+func startDoQServer(t *testing.T, cert tls.Certificate, conns *atomic.Int32) int {
+	t.Helper()
+
+	ln, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{doqALPN},
+	}, nil)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go serveDoQConn(conn)
+		}
+	}()
+	return ln.Addr().(*net.UDPAddr).Port
+}
+
+func serveDoQConn(conn *quic.Conn) {
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(stream, length[:]); err != nil {
+			stream.Close()
+			continue
+		}
+		packed := make([]byte, binary.BigEndian.Uint16(length[:]))
+		query := new(dns.Msg)
+		if _, err := io.ReadFull(stream, packed); err != nil || query.Unpack(packed) != nil {
+			stream.Close()
+			continue
+		}
+
+		m := new(dns.Msg)
+		m.SetReply(query)
+		rr, _ := dns.NewRR(query.Question[0].Name + " 60 IN A 192.0.2.1")
+		m.Answer = []dns.RR{rr}
+		reply, _ := m.Pack()
+
+		frame := make([]byte, 2+len(reply))
+		binary.BigEndian.PutUint16(frame, uint16(len(reply)))
+		copy(frame[2:], reply)
+		stream.Write(frame)
+		stream.Close()
+	}
+}
+
+func TestExecuteQueryDoQReusesConnection(t *testing.T) {
+	cert := newTestCert(t, "doq")
+	var conns atomic.Int32
+	port := startDoQServer(t, cert, &conns)
+
+	// Follow-up NS lookups must go to the plain resolver, not the QUIC port
+	var plain atomic.Int32
+	resolverPort := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		plain.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+	})
+
+	cfg := testConfig(port)
+	cfg.TLSIPv4.SPKIPin = spkiPin(t, cert.Certificate[0])
+	cfg.ResolverPort = resolverPort
+
+	session := NewSession()
+	defer session.Close()
+
+	for _, domain := range []string{"a.example.com", "b.example.com"} {
+		spec := QuerySpec{Domain: domain, QueryType: QueryTypeA, Transport: DoQ, IPVersion: IPv4}
+		res := session.ExecuteQuery(spec, cfg)
+		if len(res.ResolvedIPs) != 1 || res.ResolvedIPs[0] != "192.0.2.1" {
+			t.Fatalf("%s: resolved %v (status %s, error %s), want [192.0.2.1]", domain, res.ResolvedIPs, res.Status, res.Error)
+		}
+		if res.ALPN != doqALPN {
+			t.Errorf("%s: ALPN = %q, want %q", domain, res.ALPN, doqALPN)
+		}
+	}
+
+	if n := conns.Load(); n != 1 {
+		t.Errorf("server accepted %d connections, want 1", n)
+	}
+	if plain.Load() == 0 {
+		t.Error("no follow-up lookups reached the plain resolver port")
+	}
+}
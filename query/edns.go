@@ -0,0 +1,165 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// applyEDNS adds the OPT record and any EDNS options to an outgoing query.
+// A client subnet requires EDNS, so it turns EDNS on even if it was disabled.
+// Every EDNS query asks for the server's NSID (RFC 5001).
+func applyEDNS(msg *dns.Msg, opts config.EDNSOptions, clientSubnet string) error {
+	var subnet *dns.EDNS0_SUBNET
+	if clientSubnet != "" {
+		var err error
+		subnet, err = newClientSubnetOption(clientSubnet)
+		if err != nil {
+			return err
+		}
+		opts.Enabled = true
+	}
+
+	if !opts.Enabled {
+		return nil
+	}
+
+	msg.SetEdns0(opts.UDPSize, opts.DO)
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID})
+	if subnet != nil {
+		opt.Option = append(opt.Option, subnet)
+	}
+
+	return nil
+}
+
+// newClientSubnetOption builds an ECS option (RFC 7871) from CIDR notation.
+// A bare address is treated as a host prefix (/32 or /128).
+func newClientSubnetOption(subnet string) (*dns.EDNS0_SUBNET, error) {
+	if !strings.Contains(subnet, "/") {
+		ip := net.ParseIP(subnet)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid client subnet '%s'", subnet)
+		}
+		if ip.To4() != nil {
+			subnet += "/32"
+		} else {
+			subnet += "/128"
+		}
+	}
+
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client subnet '%s'", subnet)
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	option := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(ones),
+	}
+
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		option.Family = 1
+		option.Address = ip4
+	} else {
+		option.Family = 2
+		option.Address = ipNet.IP
+	}
+
+	return option, nil
+}
+
+// readEDNS extracts the server's OPT record details from a response
+func readEDNS(response *dns.Msg) (*result.EDNSInfo, *result.ECSInfo) {
+	opt := response.IsEdns0()
+	if opt == nil {
+		return nil, nil
+	}
+
+	info := &result.EDNSInfo{
+		UDPSize: opt.UDPSize(),
+		Version: opt.Version(),
+		DO:      opt.Do(),
+	}
+
+	var ecs *result.ECSInfo
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			ecs = &result.ECSInfo{
+				Subnet:       subnet.Address.String() + "/" + strconv.Itoa(int(subnet.SourceNetmask)),
+				SourcePrefix: subnet.SourceNetmask,
+				ScopePrefix:  subnet.SourceScope,
+			}
+		}
+	}
+
+	return info, ecs
+}
+
+// readServerOptions extracts the NSID (RFC 5001) and any Extended DNS Errors
+// (RFC 8914) from a response. A printable NSID is returned as text, anything
+// else as hex.
+func readServerOptions(response *dns.Msg) (string, []result.EDEInfo) {
+	opt := response.IsEdns0()
+	if opt == nil {
+		return "", nil
+	}
+
+	var nsid string
+	var ede []result.EDEInfo
+	for _, option := range opt.Option {
+		switch o := option.(type) {
+		case *dns.EDNS0_NSID:
+			nsid = o.Nsid
+			if raw, err := hex.DecodeString(o.Nsid); err == nil && isPrintable(raw) {
+				nsid = string(raw)
+			}
+		case *dns.EDNS0_EDE:
+			ede = append(ede, result.EDEInfo{
+				Code:    o.InfoCode,
+				Purpose: dns.ExtendedErrorCodeToString[o.InfoCode],
+				Text:    o.ExtraText,
+			})
+		}
+	}
+
+	return nsid, ede
+}
+
+// isPrintable reports whether data is non-empty printable ASCII
+func isPrintable(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if b < 0x20 || b > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// describeEDE formats Extended DNS Errors for an error message,
+// e.g. "EDE 6 DNSSEC Bogus: signature expired"
+func describeEDE(ede []result.EDEInfo) string {
+	parts := make([]string, 0, len(ede))
+	for _, e := range ede {
+		part := fmt.Sprintf("EDE %d", e.Code)
+		if e.Purpose != "" {
+			part += " " + e.Purpose
+		}
+		if e.Text != "" {
+			part += ": " + e.Text
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,196 @@
+package query
+
+import (
+	"dns_query_utility/result"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// echoServer answers with the query's ECS option echoed at scope /16 when
+// the query has an OPT record
+func echoServer(t *testing.T) int {
+	return startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{mustRR(t, "www.example.com. 300 IN A 192.0.2.1")}
+		if opt := r.IsEdns0(); opt != nil {
+			m.SetEdns0(1232, false)
+			reply := m.IsEdns0()
+			for _, option := range opt.Option {
+				if o, ok := option.(*dns.EDNS0_SUBNET); ok {
+					echo := *o
+					echo.SourceScope = 16
+					reply.Option = append(reply.Option, &echo)
+				}
+			}
+		}
+		w.WriteMsg(m)
+	})
+}
+
+func TestExecuteQueryClientSubnet(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    string // --client-subnet
+		perQuery  string // client_subnet column
+		noEDNS    bool
+		want      *result.ECSInfo
+		wantError string
+	}{
+		{name: "none"},
+		{
+			name:   "global subnet",
+			global: "192.0.2.0/24",
+			want:   &result.ECSInfo{Requested: "192.0.2.0/24", Subnet: "192.0.2.0/24", SourcePrefix: 24, ScopePrefix: 16},
+		},
+		{
+			name:     "per-query address overrides the global subnet",
+			global:   "192.0.2.0/24",
+			perQuery: "2001:db8::1",
+			want:     &result.ECSInfo{Requested: "2001:db8::1", Subnet: "2001:db8::1/128", SourcePrefix: 128, ScopePrefix: 16},
+		},
+		{name: "per-query off", global: "192.0.2.0/24", perQuery: "off"},
+		{
+			name:   "turns EDNS on",
+			global: "198.51.100.7",
+			noEDNS: true,
+			want:   &result.ECSInfo{Requested: "198.51.100.7", Subnet: "198.51.100.7/32", SourcePrefix: 32, ScopePrefix: 16},
+		},
+		{name: "invalid subnet", perQuery: "192.0.2.0/33", wantError: "invalid client subnet '192.0.2.0/33'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := echoServer(t)
+			cfg := testConfig(port)
+			cfg.ClientSubnet = tt.global
+			cfg.EDNS.Enabled = !tt.noEDNS
+
+			spec := QuerySpec{Domain: "www.example.com", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4, ClientSubnet: tt.perQuery}
+			res := ExecuteQuery(spec, cfg)
+
+			if res.Error != tt.wantError {
+				t.Fatalf("error = %q, want %q", res.Error, tt.wantError)
+			}
+			switch {
+			case tt.want == nil && res.ECS != nil:
+				t.Errorf("ecs = %+v, want none", res.ECS)
+			case tt.want != nil && (res.ECS == nil || *res.ECS != *tt.want):
+				t.Errorf("ecs = %+v, want %+v", res.ECS, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientSubnetOption(t *testing.T) {
+	tests := []struct {
+		subnet  string
+		family  uint16
+		netmask uint8
+		address string
+	}{
+		{"192.0.2.0/24", 1, 24, "192.0.2.0"},
+		{"192.0.2.77/24", 1, 24, "192.0.2.0"},
+		{"192.0.2.1", 1, 32, "192.0.2.1"},
+		{"2001:db8::/56", 2, 56, "2001:db8::"},
+		{"2001:db8::1", 2, 128, "2001:db8::1"},
+		{"not-an-ip", 0, 0, ""},
+		{"192.0.2.0/40", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		option, err := newClientSubnetOption(tt.subnet)
+		if tt.address == "" {
+			if err == nil || !strings.Contains(err.Error(), tt.subnet) {
+				t.Errorf("%s: error = %v, want an invalid subnet error", tt.subnet, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.subnet, err)
+			continue
+		}
+		if option.Family != tt.family || option.SourceNetmask != tt.netmask || option.Address.String() != tt.address {
+			t.Errorf("%s: family %d, netmask %d, address %s; want %d, %d, %s",
+				tt.subnet, option.Family, option.SourceNetmask, option.Address, tt.family, tt.netmask, tt.address)
+		}
+	}
+}
+
+func TestReadServerOptions(t *testing.T) {
+	withOptions := func(options ...dns.EDNS0) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetEdns0(1232, false)
+		m.IsEdns0().Option = options
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		response *dns.Msg
+		wantNSID string
+		wantEDE  []result.EDEInfo
+	}{
+		{name: "no OPT record", response: new(dns.Msg)},
+		{name: "printable NSID", response: withOptions(&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "6e73312e6c6178"}), wantNSID: "ns1.lax"},
+		{name: "binary NSID", response: withOptions(&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "00ff"}), wantNSID: "00ff"},
+		{
+			name: "extended errors",
+			response: withOptions(
+				&dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeDNSBogus, ExtraText: "signature expired"},
+				&dns.EDNS0_EDE{InfoCode: 65000},
+			),
+			wantEDE: []result.EDEInfo{{Code: 6, Purpose: "DNSSEC Bogus", Text: "signature expired"}, {Code: 65000}},
+		},
+	}
+
+	for _, tt := range tests {
+		nsid, ede := readServerOptions(tt.response)
+		if nsid != tt.wantNSID {
+			t.Errorf("%s: nsid = %q, want %q", tt.name, nsid, tt.wantNSID)
+		}
+		if len(ede) != len(tt.wantEDE) {
+			t.Errorf("%s: ede = %+v, want %+v", tt.name, ede, tt.wantEDE)
+			continue
+		}
+		for i := range ede {
+			if ede[i] != tt.wantEDE[i] {
+				t.Errorf("%s: ede[%d] = %+v, want %+v", tt.name, i, ede[i], tt.wantEDE[i])
+			}
+		}
+	}
+}
+
+func TestExecuteQueryServerOptions(t *testing.T) {
+	port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		m.SetEdns0(1232, false)
+		reply := m.IsEdns0()
+		// The server only identifies itself when asked
+		if opt := r.IsEdns0(); opt != nil {
+			for _, option := range opt.Option {
+				if _, ok := option.(*dns.EDNS0_NSID); ok {
+					reply.Option = append(reply.Option, &dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "6e73312e6c6178"})
+				}
+			}
+		}
+		reply.Option = append(reply.Option, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeDNSBogus, ExtraText: "signature expired"})
+		w.WriteMsg(m)
+	})
+
+	spec := QuerySpec{Domain: "www.example.com", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+	res := ExecuteQuery(spec, testConfig(port))
+
+	if res.NSID != "ns1.lax" {
+		t.Errorf("nsid = %q, want ns1.lax", res.NSID)
+	}
+	if len(res.EDE) != 1 || res.EDE[0].Code != dns.ExtendedErrorCodeDNSBogus {
+		t.Errorf("ede = %+v, want DNSSEC Bogus", res.EDE)
+	}
+	if !strings.HasSuffix(res.Error, "(EDE 6 DNSSEC Bogus: signature expired)") {
+		t.Errorf("error = %q, want the EDE appended", res.Error)
+	}
+}
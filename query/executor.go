@@ -5,13 +5,24 @@ import (
 	"dns_query_utility/result"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
 )
 
+// ExecuteQuery runs a single query without reusing connections between calls
 func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
+	session := NewSession()
+	defer session.Close()
+
+	return session.ExecuteQuery(spec, cfg)
+}
+
+// ExecuteQuery runs a single query, reusing the session's open connections
+func (s *Session) ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 	startTime := time.Now()
 
 	res := result.QueryResult{
@@ -23,56 +34,139 @@ func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 		Timestamp:       startTime,
 		AuthoritativeNS: []string{}, // Initialize as empty array, never nil
 	}
+	if !spec.Class.IsIN() {
+		res.QueryClass = spec.Class.String()
+	}
 
 	// Determine DNS server and network
-	var server string
-	var network string
+	host := cfg.DNSServerIPv4
+	tlsOptions := cfg.TLSIPv4
+	if spec.IPVersion == IPv6 {
+		host = cfg.DNSServerIPv6
+		tlsOptions = cfg.TLSIPv6
+	}
 
-	if spec.IPVersion.String() == "ipv4" {
-		server = net.JoinHostPort(cfg.DNSServerIPv4, fmt.Sprintf("%d", cfg.DNSPort))
-		if spec.Transport.String() == "udp" {
-			network = "udp"
-		} else {
-			network = "tcp"
-		}
-	} else {
-		server = net.JoinHostPort(cfg.DNSServerIPv6, fmt.Sprintf("%d", cfg.DNSPort))
-		if spec.Transport.String() == "udp" {
-			network = "udp6"
-		} else {
-			network = "tcp6"
-		}
+	server := net.JoinHostPort(host, strconv.Itoa(serverPort(spec.Transport, cfg)))
+
+	// Dynamic updates change the zone and then verify the change with a query
+	if spec.Update != nil {
+		return executeUpdate(spec, cfg, res, server, host, tlsOptions)
+	}
+
+	// Zone transfers stream several messages instead of a single exchange
+	if spec.QueryType.IsTransfer() {
+		return executeTransfer(spec, cfg, res, server, host, tlsOptions)
 	}
 
 	// Create DNS message
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(spec.Domain), uint16(spec.QueryType))
+	msg.Question[0].Qclass = spec.Class.WireValue()
 	msg.RecursionDesired = true
 
+	// Delegation-based modes only make sense for the IN class; CHAOS and
+	// Hesiod queries are answered by the server itself
+	trace := cfg.Trace && spec.Class.IsIN()
+
+	clientSubnet := spec.ClientSubnet
+	if clientSubnet == "" {
+		clientSubnet = cfg.ClientSubnet
+	}
+	if strings.EqualFold(clientSubnet, "off") {
+		clientSubnet = ""
+	}
+	if err := applyEDNS(msg, spec.EDNS.Resolve(cfg.EDNS), clientSubnet); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	// Sign a copy of the query so trace and authoritative lookups stay unsigned
+	tsigKey, err := resolveTSIG(spec, cfg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	query := msg
+	if tsigKey.Name != "" {
+		query = signQuery(msg, tsigKey)
+	}
+
 	// Create DNS client
 	client := &dns.Client{
-		Net:     network,
-		Timeout: cfg.Timeout,
+		Net:        spec.Transport.Network(spec.IPVersion),
+		Timeout:    cfg.Timeout,
+		TsigSecret: tsigSecrets(cfg),
+	}
+
+	if spec.Transport == DoT {
+		tlsConfig, err := newTLSConfig(tlsOptions, host)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		client.TLSConfig = tlsConfig
 	}
 
 	// Execute query with retries
 	var response *dns.Msg
-	var err error
 
-	for attempt := 0; attempt <= cfg.RetryCount; attempt++ {
-		response, _, err = client.Exchange(msg, server)
-		if err == nil {
-			break
+	if trace {
+		// Follow referrals from the root instead of asking the resolver
+		response, res.Trace, err = traceQuery(msg, spec, cfg)
+	} else {
+		for attempt := 0; attempt <= cfg.RetryCount; attempt++ {
+			switch spec.Transport {
+			case DoH:
+				response, err = exchangeDoH(query, spec, cfg)
+			case DoQ:
+				var state quic.ConnectionState
+				response, state, err = s.exchangeDoQ(query, server, spec, cfg, tlsOptions)
+				if err == nil || isTSIGError(err) {
+					res.ALPN = state.TLS.NegotiatedProtocol
+					res.Used0RTT = state.Used0RTT
+				}
+			default:
+				response, _, err = client.Exchange(query, server)
+			}
+			// A bad signature will not improve on retry
+			if err == nil || isTSIGError(err) {
+				break
+			}
+			if attempt == cfg.RetryCount {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
 		}
-		if attempt == cfg.RetryCount {
-			break
+	}
+
+	// A truncated UDP answer is incomplete; repeat it over TCP unless disabled
+	if err == nil && response.Truncated && spec.Transport == UDP && !trace {
+		res.Truncated = true
+		if cfg.TCPFallback {
+			res.FallbackTransport = TCP.String()
+			tcpClient := &dns.Client{
+				Net:        TCP.Network(spec.IPVersion),
+				Timeout:    cfg.Timeout,
+				TsigSecret: tsigSecrets(cfg),
+			}
+			response, _, err = tcpClient.Exchange(query, server)
+			if err != nil && !isTSIGError(err) {
+				err = fmt.Errorf("tcp fallback failed: %w", err)
+			}
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	// Convert nanoseconds to milliseconds (float64)
 	res.LatencyMs = float64(time.Since(startTime).Nanoseconds()) / 1e6
 
+	// A signed query needs a validly signed response
+	if tsigKey.Name != "" && !trace {
+		res.TSIG, err = verifyTSIG(response, tsigKey, err)
+		if err != nil && response != nil {
+			res.ResponseCode = response.Rcode
+		}
+	}
+
 	if err != nil {
 		res.Error = err.Error()
 		if strings.Contains(err.Error(), "timeout") {
@@ -84,21 +178,40 @@ func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 	}
 
 	res.ResponseCode = response.Rcode
+	res.Header, res.ResponseSize = readHeader(response)
+	res.TTLs, res.MinTTL = answerTTLs(response.Answer)
+
+	// Keep every section as the server sent it, whatever the response code
+	res.Answers = newRecords(response.Answer)
+	res.Authority = newRecords(response.Ns)
+	res.Additional = newRecords(response.Extra)
+
+	// Record the server's OPT record and ECS scope, if it sent them
+	res.EDNS, res.ECS = readEDNS(response)
+	if res.ECS != nil {
+		res.ECS.Requested = clientSubnet
+	}
+	res.NSID, res.EDE = readServerOptions(response)
 
 	// Extract authoritative nameservers from Authority AND Additional sections
 	res.AuthoritativeNS = extractAuthoritativeNS(response.Ns, response.Extra)
 
+	// Follow-up lookups go through the plain (unencrypted) resolver address
+	resolver := resolverAddress(host, spec.Transport, cfg)
+
 	switch response.Rcode {
 	case dns.RcodeSuccess:
 		if len(response.Answer) == 0 {
 			res.Status = result.StatusNoAnswer
-			allRecords := append(response.Ns, response.Extra...)
-			if len(allRecords) > 0 {
-				records := extractRecords(allRecords)
-				res.Records = records
-				res.Error = fmt.Sprintf("no A/AAAA records found (got: %v)", records)
-			} else {
-				res.Error = "no records found"
+			res.Records = extractRecords(append(response.Ns, response.Extra...))
+			res.Negative = analyzeNegative(response, spec, cfg, resolver)
+			switch res.Negative.Kind {
+			case result.NegativeEmptyNonTerminal:
+				res.Error = "name has no records, only names below it (empty non-terminal)"
+			case result.NegativeReferral:
+				res.Error = fmt.Sprintf("referral to %s instead of an answer", res.Negative.Zone)
+			default:
+				res.Error = fmt.Sprintf("no %s records found", spec.QueryType)
 			}
 		} else {
 			// parseAnswers handles all record types (A, AAAA, MX, TXT, etc.)
@@ -118,6 +231,7 @@ func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 	case dns.RcodeNameError:
 		res.Status = result.StatusNXDomain
 		res.Error = "domain does not exist"
+		res.Negative = analyzeNegative(response, spec, cfg, resolver)
 
 	case dns.RcodeServerFailure:
 		res.Status = result.StatusServFail
@@ -132,9 +246,52 @@ func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 		res.Error = fmt.Sprintf("unexpected response code: %d", response.Rcode)
 	}
 
+	// Record the aliases followed to reach the answer, and who returned them
+	answeredBy := server
+	switch {
+	case trace:
+		answeredBy = tracedServer(res.Trace, server)
+	case spec.Transport == DoH:
+		answeredBy = cfg.DoHURL
+	}
+	res.CNAMEChain = resolveCNAMEChain(response, spec, cfg, answeredBy, resolver)
+
+	// Add the reason the server gave (e.g. DNSSEC bogus, blocked) to any failure
+	if len(res.EDE) > 0 && res.Status != result.StatusSuccess && res.Error != "" {
+		res.Error = fmt.Sprintf("%s (%s)", res.Error, describeEDE(res.EDE))
+	}
+
+	// CHAOS and Hesiod names are not delegated, so the zone checks below do not apply
+	if !spec.Class.IsIN() {
+		if res.AuthoritativeNS == nil {
+			res.AuthoritativeNS = []string{}
+		}
+		return res
+	}
+
+	// Walk the chain of trust from the root
+	if cfg.DNSSECValidate {
+		res.DNSSEC = s.validateDNSSEC(spec, cfg, resolver)
+	}
+
+	// Ask every authoritative server directly and compare their answers
+	if cfg.Authoritative {
+		res.Authoritative = s.queryAuthoritative(msg, spec, cfg, resolver)
+	}
+
+	// Compare the zone's SOA across all of its authoritative servers
+	if cfg.ZoneHealth {
+		res.ZoneHealth = s.checkZoneHealth(spec, cfg, resolver)
+	}
+
+	// Compare the parent's delegation with the zone's own NS set and glue
+	if cfg.Delegation {
+		res.Delegation = s.checkDelegation(spec, cfg, resolver)
+	}
+
 	// If no authoritative NS found in response, do a separate NS lookup
 	if len(res.AuthoritativeNS) == 0 {
-		res.AuthoritativeNS = lookupAuthoritativeNS(spec.Domain, cfg)
+		res.AuthoritativeNS = lookupAuthoritativeNS(spec.Domain, cfg, resolverAddress(cfg.DNSServerIPv4, spec.Transport, cfg))
 	}
 
 	// Guarantee it's never nil (always return at least empty array)
@@ -145,24 +302,102 @@ func ExecuteQuery(spec QuerySpec, cfg config.Config) result.QueryResult {
 	return res
 }
 
-// getBaseDomain extracts the registrable domain for NS lookup
-func getBaseDomain(domain string) string {
+// serverPort returns the port queries with transport are sent to: the --dns
+// port, except that DoT and DoQ use 853 when it was left at 53
+func serverPort(transport Transport, cfg config.Config) int {
+	if cfg.DNSPort != config.DefaultDNSPort {
+		return cfg.DNSPort
+	}
+	switch transport {
+	case DoT:
+		return config.DefaultDoTPort
+	case DoQ:
+		return config.DefaultDoQPort
+	}
+	return cfg.DNSPort
+}
+
+// resolverAddress returns the plain DNS address of the resolver at host, used
+// for follow-up lookups (SOA, NS, CNAME, ENT probe, takeover). The --dns port
+// only applies when the query itself was plain DNS; for DoT, DoQ and DoH it
+// is the encrypted port, so --resolver-port or 53 is used instead.
+func resolverAddress(host string, transport Transport, cfg config.Config) string {
+	port := cfg.ResolverPort
+	if port == 0 {
+		port = config.DefaultDNSPort
+		if transport == UDP || transport == TCP {
+			port = cfg.DNSPort
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// getBaseDomain extracts the domain to look up NS records for: the zone cut
+// found by walking SOA queries in --zone-cut soa mode, otherwise the
+// registrable domain according to the Public Suffix List
+// e.g., "www.example.co.uk" -> "example.co.uk"
+func getBaseDomain(domain string, cfg config.Config, resolver string) string {
 	domain = strings.TrimSuffix(domain, ".")
-	parts := strings.Split(domain, ".")
 
-	// Handle special cases
-	if len(parts) <= 2 {
+	if cfg.ZoneCut == config.ZoneCutSOA {
+		if zone, err := findZoneCut(dns.Fqdn(domain), IPv4, cfg, resolver); err == nil {
+			return zone
+		}
+	}
+
+	list, err := LoadPublicSuffixList(cfg.PSLFile)
+	if err != nil {
 		return domain
 	}
 
-	// For subdomains, return the base domain (last 2 parts)
-	// e.g., "www.example.com" -> "example.com"
-	return strings.Join(parts[len(parts)-2:], ".")
+	// A domain that is itself a public suffix (e.g. "co.uk") is used as is
+	if registrable := list.RegistrableDomain(domain); registrable != "" {
+		return registrable
+	}
+	return domain
+}
+
+// findZoneCut walks up from name with SOA queries through the resolver until
+// it reaches the apex of the zone containing name. An SOA in the authority
+// section of a negative answer names the enclosing zone directly.
+func findZoneCut(name string, ipv IPVersion, cfg config.Config, resolver string) (string, error) {
+	for zone := dns.CanonicalName(name); ; zone = parentZone(zone) {
+		resp, err := resolverLookup(zone, dns.TypeSOA, ipv, cfg, resolver)
+		if err != nil {
+			return "", fmt.Errorf("SOA lookup for %s failed: %w", zone, err)
+		}
+
+		aliased := false
+		for _, rr := range resp.Answer {
+			switch record := rr.(type) {
+			case *dns.SOA:
+				if dns.CanonicalName(record.Hdr.Name) == zone {
+					return zone, nil
+				}
+			case *dns.CNAME:
+				aliased = true
+			}
+		}
+
+		// The authority SOA of an alias belongs to the target's zone, so keep walking
+		if !aliased {
+			for _, rr := range resp.Ns {
+				if soa, ok := rr.(*dns.SOA); ok && dns.IsSubDomain(dns.CanonicalName(soa.Hdr.Name), zone) {
+					return dns.CanonicalName(soa.Hdr.Name), nil
+				}
+			}
+		}
+
+		if zone == "." {
+			return "", fmt.Errorf("cannot locate zone of %s", name)
+		}
+	}
 }
 
-// lookupAuthoritativeNS performs a separate NS query to find authoritative nameservers
-func lookupAuthoritativeNS(domain string, cfg config.Config) []string {
-	baseDomain := getBaseDomain(domain)
+// lookupAuthoritativeNS performs a separate NS query to find authoritative
+// nameservers through the plain IPv4 resolver address
+func lookupAuthoritativeNS(domain string, cfg config.Config, resolver string) []string {
+	baseDomain := getBaseDomain(domain, cfg, resolver)
 
 	// Create NS query
 	msg := new(dns.Msg)
@@ -170,14 +405,13 @@ func lookupAuthoritativeNS(domain string, cfg config.Config) []string {
 	msg.RecursionDesired = true
 
 	// Use IPv4 UDP for NS lookup (most reliable)
-	server := net.JoinHostPort(cfg.DNSServerIPv4, fmt.Sprintf("%d", cfg.DNSPort))
 	client := &dns.Client{
 		Net:     "udp",
 		Timeout: cfg.Timeout,
 	}
 
 	// Execute NS query
-	resp, _, err := client.Exchange(msg, server)
+	resp, _, err := client.Exchange(msg, resolver)
 	if err != nil || resp == nil {
 		return []string{}
 	}
@@ -242,6 +476,50 @@ func extractAuthoritativeNS(authority []dns.RR, additional []dns.RR) []string {
 	return nsRecords
 }
 
+// readHeader returns the response's ID and flags and its size in bytes. The
+// size is that of the message encoded with name compression, as servers send it.
+func readHeader(response *dns.Msg) (*result.HeaderInfo, int) {
+	header := &result.HeaderInfo{
+		ID: response.Id,
+		AA: response.Authoritative,
+		TC: response.Truncated,
+		RD: response.RecursionDesired,
+		RA: response.RecursionAvailable,
+		AD: response.AuthenticatedData,
+		CD: response.CheckingDisabled,
+	}
+
+	compress := response.Compress
+	response.Compress = true
+	size := response.Len()
+	response.Compress = compress
+
+	return header, size
+}
+
+// answerTTLs lists the TTL of every answer record and the lowest of them
+func answerTTLs(answers []dns.RR) ([]result.RecordTTL, *uint32) {
+	if len(answers) == 0 {
+		return nil, nil
+	}
+
+	ttls := make([]result.RecordTTL, 0, len(answers))
+	minTTL := answers[0].Header().Ttl
+	for _, rr := range answers {
+		hdr := rr.Header()
+		ttls = append(ttls, result.RecordTTL{
+			Name: hdr.Name,
+			Type: dns.TypeToString[hdr.Rrtype],
+			TTL:  hdr.Ttl,
+		})
+		if hdr.Ttl < minTTL {
+			minTTL = hdr.Ttl
+		}
+	}
+
+	return ttls, &minTTL
+}
+
 func parseAnswers(answers []dns.RR) ([]string, []string) {
 	var ips []string
 	var records []string
@@ -267,6 +545,25 @@ func parseAnswers(answers []dns.RR) ([]string, []string) {
 		case *dns.SRV:
 			records = append(records, fmt.Sprintf("SRV:%d %d %d %s",
 				rr.Priority, rr.Weight, rr.Port, rr.Target))
+		case *dns.CAA:
+			records = append(records, fmt.Sprintf("CAA:%d %s \"%s\"", rr.Flag, rr.Tag, rr.Value))
+		case *dns.DNSKEY:
+			records = append(records, formatDNSKEY(rr))
+		case *dns.DS:
+			records = append(records, formatDS(rr))
+		case *dns.HTTPS:
+			records = append(records, fmt.Sprintf("HTTPS:%s", formatSVCB(&rr.SVCB)))
+		case *dns.SVCB:
+			records = append(records, fmt.Sprintf("SVCB:%s", formatSVCB(rr)))
+		case *dns.TLSA:
+			records = append(records, fmt.Sprintf("TLSA:%d %d %d %s",
+				rr.Usage, rr.Selector, rr.MatchingType, strings.ToLower(rr.Certificate)))
+		case *dns.NAPTR:
+			records = append(records, fmt.Sprintf("NAPTR:%d %d \"%s\" \"%s\" \"%s\" %s",
+				rr.Order, rr.Preference, rr.Flags, rr.Service, rr.Regexp, rr.Replacement))
+		case *dns.SSHFP:
+			records = append(records, fmt.Sprintf("SSHFP:%d %d %s",
+				rr.Algorithm, rr.Type, strings.ToLower(rr.FingerPrint)))
 		default:
 			records = append(records, fmt.Sprintf("%s:%s", dns.TypeToString[rr.Header().Rrtype], rr.String()))
 		}
@@ -275,10 +572,34 @@ func parseAnswers(answers []dns.RR) ([]string, []string) {
 	return ips, records
 }
 
+// formatDNSKEY renders a DNSKEY record as "DNSKEY:flags protocol algorithm key (tag N)"
+func formatDNSKEY(rr *dns.DNSKEY) string {
+	return fmt.Sprintf("DNSKEY:%d %d %s %s (tag %d)",
+		rr.Flags, rr.Protocol, dns.AlgorithmToString[rr.Algorithm], rr.PublicKey, rr.KeyTag())
+}
+
+// formatDS renders a DS record as "DS:tag algorithm digest-type digest"
+func formatDS(rr *dns.DS) string {
+	return fmt.Sprintf("DS:%d %s %d %s",
+		rr.KeyTag, dns.AlgorithmToString[rr.Algorithm], rr.DigestType, strings.ToLower(rr.Digest))
+}
+
+// formatSVCB renders an SVCB/HTTPS record as "priority target key=value ..."
+func formatSVCB(rr *dns.SVCB) string {
+	parts := []string{fmt.Sprintf("%d %s", rr.Priority, rr.Target)}
+	for _, kv := range rr.Value {
+		parts = append(parts, fmt.Sprintf("%s=%s", kv.Key(), kv.String()))
+	}
+	return strings.Join(parts, " ")
+}
+
 func extractRecords(rrs []dns.RR) []string {
 	var records []string
 	for _, rr := range rrs {
 		switch r := rr.(type) {
+		case *dns.OPT:
+			// EDNS pseudo-record, reported separately in QueryResult.EDNS
+			continue
 		case *dns.MX:
 			records = append(records, fmt.Sprintf("MX:%d %s", r.Preference, r.Mx))
 		case *dns.NS:
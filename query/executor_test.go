@@ -0,0 +1,161 @@
+package query
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestExecuteQueryKeepsSectionsForEveryRcode(t *testing.T) {
+	port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Rcode = dns.RcodeNameError
+		cname, _ := dns.NewRR("app.example.com. 60 IN CNAME gone.example.net.")
+		soa, _ := dns.NewRR("example.net. 3600 IN SOA ns1.example.net. h.example.net. 1 7200 3600 1209600 300")
+		m.Answer = []dns.RR{cname}
+		m.Ns = []dns.RR{soa}
+		w.WriteMsg(m)
+	})
+
+	spec := QuerySpec{Domain: "app.example.com", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+	res := ExecuteQuery(spec, testConfig(port))
+
+	if res.ResponseCode != dns.RcodeNameError {
+		t.Fatalf("response code = %d, want NXDOMAIN (error: %s)", res.ResponseCode, res.Error)
+	}
+	if len(res.Answers) != 1 || res.Answers[0].Target != "gone.example.net." {
+		t.Errorf("answers = %+v, want the CNAME to gone.example.net.", res.Answers)
+	}
+	if len(res.Authority) != 1 || res.Authority[0].SOA == nil {
+		t.Errorf("authority = %+v, want the SOA", res.Authority)
+	}
+}
+
+func TestServerAndResolverPorts(t *testing.T) {
+	tests := []struct {
+		transport    Transport
+		dnsPort      int
+		resolverPort int
+		wantServer   int
+		wantResolver string
+	}{
+		{UDP, 53, 0, 53, "192.0.2.53:53"},
+		{TCP, 5353, 0, 5353, "192.0.2.53:5353"},
+		{DoT, 53, 0, 853, "192.0.2.53:53"},
+		{DoT, 853, 0, 853, "192.0.2.53:53"},
+		{DoQ, 53, 0, 853, "192.0.2.53:53"},
+		{DoQ, 8853, 0, 8853, "192.0.2.53:53"},
+		{DoH, 53, 0, 53, "192.0.2.53:53"},
+		{DoT, 853, 5353, 853, "192.0.2.53:5353"},
+		{UDP, 5353, 5300, 5353, "192.0.2.53:5300"},
+	}
+
+	for _, tt := range tests {
+		cfg := testConfig(tt.dnsPort)
+		cfg.ResolverPort = tt.resolverPort
+
+		if got := serverPort(tt.transport, cfg); got != tt.wantServer {
+			t.Errorf("%s on %d: server port = %d, want %d", tt.transport, tt.dnsPort, got, tt.wantServer)
+		}
+		if got := resolverAddress("192.0.2.53", tt.transport, cfg); got != tt.wantResolver {
+			t.Errorf("%s on %d with resolver port %d: resolver = %s, want %s",
+				tt.transport, tt.dnsPort, tt.resolverPort, got, tt.wantResolver)
+		}
+	}
+}
+
+func TestTCPFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		truncate     bool
+		fallback     bool
+		wantTCP      bool // The answer came from the TCP retry
+		wantFallback string
+		wantError    string
+	}{
+		{name: "complete answer", truncate: false, fallback: true},
+		{name: "truncated with fallback", truncate: true, fallback: true, wantTCP: true, wantFallback: "tcp"},
+		{name: "truncated without fallback", truncate: true, fallback: false, wantError: "no A records found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tcpQueries atomic.Int32
+			port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				if w.LocalAddr().Network() == "tcp" {
+					tcpQueries.Add(1)
+					m.Answer = []dns.RR{mustRR(t, "www.example.com. 300 IN A 192.0.2.2")}
+				} else if tt.truncate {
+					m.Truncated = true
+				} else {
+					m.Answer = []dns.RR{mustRR(t, "www.example.com. 300 IN A 192.0.2.1")}
+				}
+				w.WriteMsg(m)
+			})
+			cfg := testConfig(port)
+			cfg.TCPFallback = tt.fallback
+
+			spec := QuerySpec{Domain: "www.example.com", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+			res := ExecuteQuery(spec, cfg)
+
+			if res.Error != tt.wantError {
+				t.Fatalf("error = %q, want %q", res.Error, tt.wantError)
+			}
+			if res.Truncated != tt.truncate || res.FallbackTransport != tt.wantFallback {
+				t.Errorf("truncated = %v, fallback = %q; want %v, %q",
+					res.Truncated, res.FallbackTransport, tt.truncate, tt.wantFallback)
+			}
+			if n := tcpQueries.Load(); (n == 1) != tt.wantTCP {
+				t.Errorf("%d TCP queries, want retry %v", n, tt.wantTCP)
+			}
+
+			switch {
+			case tt.wantTCP:
+				if len(res.Answers) != 1 || res.Answers[0].Address != "192.0.2.2" || res.Header.TC {
+					t.Errorf("answers = %+v, TC = %v; want the TCP answer", res.Answers, res.Header.TC)
+				}
+			case tt.truncate:
+				if len(res.Answers) != 0 || !res.Header.TC {
+					t.Errorf("answers = %+v, TC = %v; want the truncated UDP response", res.Answers, res.Header.TC)
+				}
+			default:
+				if len(res.Answers) != 1 || res.Answers[0].Address != "192.0.2.1" {
+					t.Errorf("answers = %+v, want the UDP answer", res.Answers)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteQueryChaosClass(t *testing.T) {
+	port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if q := r.Question[0]; q.Qclass == dns.ClassCHAOS && q.Name == "id.server." && q.Qtype == dns.TypeTXT {
+			m.Answer = []dns.RR{mustRR(t, `id.server. 0 CH TXT "resolver-1"`)}
+		} else {
+			m.Rcode = dns.RcodeRefused
+		}
+		w.WriteMsg(m)
+	})
+	cfg := testConfig(port)
+	cfg.Delegation = true
+
+	spec := QuerySpec{Domain: "id.server", QueryType: QueryTypeTXT, Class: ClassCH, Transport: UDP, IPVersion: IPv4}
+	res := ExecuteQuery(spec, cfg)
+
+	if res.Error != "" || res.QueryClass != "CH" {
+		t.Fatalf("class = %q, error = %q", res.QueryClass, res.Error)
+	}
+	if len(res.Answers) != 1 || res.Answers[0].Class != "CH" || len(res.Answers[0].Text) != 1 || res.Answers[0].Text[0] != "resolver-1" {
+		t.Errorf("answers = %+v, want the CH TXT record", res.Answers)
+	}
+	// The name is not delegated, so the zone checks are skipped
+	if res.Delegation != nil {
+		t.Errorf("delegation = %+v for a CHAOS query", res.Delegation)
+	}
+}
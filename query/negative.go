@@ -0,0 +1,108 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+
+	"github.com/miekg/dns"
+)
+
+// analyzeNegative classifies a response without an answer and works out how
+// long it may be cached from the SOA in its authority section
+func analyzeNegative(response *dns.Msg, spec QuerySpec, cfg config.Config, resolver string) *result.NegativeResult {
+	neg := &result.NegativeResult{Kind: result.NegativeNoData}
+
+	var soa *dns.SOA
+	var referral string
+	for _, rr := range response.Ns {
+		switch record := rr.(type) {
+		case *dns.SOA:
+			if soa == nil {
+				soa = record
+			}
+		case *dns.NS:
+			referral = record.Hdr.Name
+		}
+	}
+
+	if soa != nil {
+		record := newRecord(soa)
+		neg.SOA = &record
+		neg.Zone = soa.Hdr.Name
+
+		// RFC 2308 section 5: the SOA TTL bounds the MINIMUM field
+		ttl := soa.Minttl
+		if soa.Hdr.Ttl < ttl {
+			ttl = soa.Hdr.Ttl
+		}
+		neg.NegativeTTL = &ttl
+	}
+
+	switch {
+	case response.Rcode == dns.RcodeNameError:
+		neg.Kind = result.NegativeNXDomain
+	case soa == nil && referral != "":
+		// NS records without an SOA delegate the name elsewhere
+		neg.Kind = result.NegativeReferral
+		neg.Zone = referral
+	default:
+		var ent bool
+		ent, neg.Evidence, neg.Inconclusive = emptyNonTerminal(response, spec, cfg, resolver, neg.Zone)
+		if ent {
+			neg.Kind = result.NegativeEmptyNonTerminal
+		}
+	}
+
+	return neg
+}
+
+// emptyNonTerminal reports whether a NODATA response is for a name that has
+// no records at all, only names below it, and what the decision was based on.
+// NSEC records in the response decide it directly; otherwise, with
+// --probe-ent, the name is queried for type ANY. The evidence is empty when it
+// could not be decided, and the result is inconclusive when the probe was
+// refused or failed.
+func emptyNonTerminal(response *dns.Msg, spec QuerySpec, cfg config.Config, resolver string, zone string) (bool, string, bool) {
+	qname := dns.CanonicalName(dns.Fqdn(spec.Domain))
+
+	// The zone apex always has its SOA and NS records
+	if zone != "" && dns.CanonicalName(zone) == qname {
+		return false, "zone_apex", false
+	}
+
+	// An NSEC owned by the name lists the types it has; an NSEC whose next
+	// name is below the name proves the name itself has nothing
+	for _, rr := range response.Ns {
+		nsec, ok := rr.(*dns.NSEC)
+		if !ok {
+			continue
+		}
+		if dns.CanonicalName(nsec.Hdr.Name) == qname {
+			return false, "nsec", false
+		}
+		next := dns.CanonicalName(nsec.NextDomain)
+		if next != qname && dns.IsSubDomain(qname, next) {
+			return true, "nsec", false
+		}
+	}
+
+	// NODATA for ANY already means there is nothing at the name
+	if spec.QueryType == QueryTypeANY {
+		return true, "query_any", false
+	}
+
+	// CHAOS and Hesiod names are answered by the server itself and have no
+	// delegated tree to probe. The probe costs an extra ANY query for every
+	// NODATA, which many servers refuse, so it only runs when asked for.
+	if !cfg.ProbeENT || !spec.Class.IsIN() {
+		return false, "", false
+	}
+
+	resp, err := resolverLookup(qname, dns.TypeANY, spec.IPVersion, cfg, resolver)
+	if err != nil || resp.Rcode != dns.RcodeSuccess {
+		return false, "probe_any", true
+	}
+	// Servers following RFC 8482 answer ANY with a single synthesized record,
+	// which still shows the name owns something
+	return len(resp.Answer) == 0, "probe_any", false
+}
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"dns_query_utility/result"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestAnalyzeNegativeNoData(t *testing.T) {
+	soa := "example. 3600 IN SOA ns.example. admin.example. 1 7200 3600 1209600 300"
+
+	tests := []struct {
+		name         string
+		qname        string
+		ns           []string
+		probeENT     bool
+		anyRcode     int
+		anyAnswer    bool
+		wantKind     result.NegativeKind
+		wantEvidence string
+		inconclusive bool
+	}{
+		{
+			name: "zone apex", qname: "example.", ns: []string{soa},
+			wantKind: result.NegativeNoData, wantEvidence: "zone_apex",
+		},
+		{
+			name: "NSEC below the name", qname: "_tcp.example.",
+			ns:       []string{soa, "a.example. 300 IN NSEC _sip._tcp.example. A RRSIG NSEC"},
+			wantKind: result.NegativeEmptyNonTerminal, wantEvidence: "nsec",
+		},
+		{
+			name: "no probe by default", qname: "_tcp.example.", ns: []string{soa},
+			wantKind: result.NegativeNoData,
+		},
+		{
+			name: "probe finds nothing", qname: "_tcp.example.", ns: []string{soa}, probeENT: true,
+			wantKind: result.NegativeEmptyNonTerminal, wantEvidence: "probe_any",
+		},
+		{
+			name: "probe finds records", qname: "www.example.", ns: []string{soa}, probeENT: true, anyAnswer: true,
+			wantKind: result.NegativeNoData, wantEvidence: "probe_any",
+		},
+		{
+			name: "probe refused", qname: "_tcp.example.", ns: []string{soa}, probeENT: true, anyRcode: dns.RcodeRefused,
+			wantKind: result.NegativeNoData, wantEvidence: "probe_any", inconclusive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probes atomic.Int32
+			port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+				probes.Add(1)
+				m := new(dns.Msg)
+				m.SetReply(r)
+				m.Rcode = tt.anyRcode
+				if tt.anyAnswer {
+					m.Answer = []dns.RR{mustRR(t, r.Question[0].Name+" 300 IN A 192.0.2.1")}
+				}
+				w.WriteMsg(m)
+			})
+			cfg := testConfig(port)
+			cfg.ProbeENT = tt.probeENT
+
+			response := new(dns.Msg)
+			response.SetQuestion(tt.qname, dns.TypeTXT)
+			for _, rr := range tt.ns {
+				response.Ns = append(response.Ns, mustRR(t, rr))
+			}
+
+			spec := QuerySpec{Domain: tt.qname, QueryType: QueryTypeTXT, Transport: UDP, IPVersion: IPv4}
+			neg := analyzeNegative(response, spec, cfg, testAddr(port))
+
+			if neg.Kind != tt.wantKind || neg.Evidence != tt.wantEvidence || neg.Inconclusive != tt.inconclusive {
+				t.Errorf("got kind %s, evidence %q, inconclusive %v; want %s, %q, %v",
+					neg.Kind, neg.Evidence, neg.Inconclusive, tt.wantKind, tt.wantEvidence, tt.inconclusive)
+			}
+			if neg.NegativeTTL == nil || *neg.NegativeTTL != 300 {
+				t.Errorf("negative TTL = %v, want 300", neg.NegativeTTL)
+			}
+			if !tt.probeENT && probes.Load() > 0 {
+				t.Errorf("sent %d probes without --probe-ent", probes.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package query
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// embeddedPSL is a snapshot of the Public Suffix List. Replace the file to
+// update it, or load a newer copy at runtime with LoadPublicSuffixList.
+//
+//go:embed public_suffix_list.dat
+var embeddedPSL string
+
+// PublicSuffixList holds the rules of a Public Suffix List (https://publicsuffix.org)
+type PublicSuffixList struct {
+	normal    map[string]bool // "co.uk"
+	wildcard  map[string]bool // "*.ck" stored as "ck"
+	exception map[string]bool // "!www.ck" stored as "www.ck"
+}
+
+var (
+	pslMu     sync.Mutex
+	pslByFile = make(map[string]*PublicSuffixList)
+)
+
+// LoadPublicSuffixList reads a list in public_suffix_list.dat format from
+// path. An empty path returns the embedded list.
+func LoadPublicSuffixList(path string) (*PublicSuffixList, error) {
+	pslMu.Lock()
+	defer pslMu.Unlock()
+
+	if list, ok := pslByFile[path]; ok {
+		return list, nil
+	}
+
+	source := embeddedPSL
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public suffix list: %w", err)
+		}
+		source = string(data)
+	}
+
+	list := ParsePublicSuffixList(source)
+	if len(list.normal)+len(list.wildcard) == 0 {
+		return nil, fmt.Errorf("public suffix list %s contains no rules", path)
+	}
+
+	pslByFile[path] = list
+	return list, nil
+}
+
+// ParsePublicSuffixList parses rules in public_suffix_list.dat format. Each
+// rule is the first whitespace-separated word of a line; comments start with "//".
+func ParsePublicSuffixList(source string) *PublicSuffixList {
+	list := &PublicSuffixList{
+		normal:    make(map[string]bool),
+		wildcard:  make(map[string]bool),
+		exception: make(map[string]bool),
+	}
+
+	for _, line := range strings.Split(source, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+		rule := strings.ToLower(strings.TrimSuffix(fields[0], "."))
+
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			list.exception[rule[1:]] = true
+		case strings.HasPrefix(rule, "*."):
+			list.wildcard[rule[2:]] = true
+		default:
+			list.normal[rule] = true
+		}
+	}
+
+	return list
+}
+
+// PublicSuffix returns the public suffix of domain using the PSL algorithm:
+// the longest matching rule wins, exception rules override wildcards, and an
+// unlisted TLD is its own public suffix. "www.example.co.uk" -> "co.uk"
+func (l *PublicSuffixList) PublicSuffix(domain string) string {
+	labels := dns.SplitDomainName(strings.ToLower(domain))
+	if len(labels) == 0 {
+		return ""
+	}
+
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+		if l.exception[candidate] {
+			return strings.Join(labels[i+1:], ".")
+		}
+		if l.normal[candidate] {
+			return candidate
+		}
+		if i+1 < len(labels) && l.wildcard[strings.Join(labels[i+1:], ".")] {
+			return candidate
+		}
+	}
+
+	return labels[len(labels)-1]
+}
+
+// RegistrableDomain returns the public suffix of domain plus one label
+// ("www.example.co.uk" -> "example.co.uk"), or "" when domain is itself a
+// public suffix
+func (l *PublicSuffixList) RegistrableDomain(domain string) string {
+	labels := dns.SplitDomainName(strings.ToLower(domain))
+	suffix := l.PublicSuffix(domain)
+	suffixLabels := dns.CountLabel(dns.Fqdn(suffix))
+	if suffix == "" || len(labels) <= suffixLabels {
+		return ""
+	}
+
+	return strings.Join(labels[len(labels)-suffixLabels-1:], ".")
+}
+
+// RegistrableDomain returns the registrable domain of domain according to
+// the embedded Public Suffix List
+func RegistrableDomain(domain string) string {
+	list, err := LoadPublicSuffixList("")
+	if err != nil {
+		return ""
+	}
+	return list.RegistrableDomain(domain)
+}
@@ -0,0 +1,65 @@
+package query
+
+import "testing"
+
+func TestParsePublicSuffixList(t *testing.T) {
+	list := ParsePublicSuffixList(`// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk   trailing text is ignored
+*.ck
+!www.ck
+
+// Private domains
+GitHub.io.
+`)
+
+	tests := []struct {
+		domain      string
+		suffix      string
+		registrable string
+	}{
+		{"example.com", "com", "example.com"},
+		{"www.example.co.uk", "co.uk", "example.co.uk"},
+		{"co.uk", "co.uk", ""},
+		{"foo.github.io", "github.io", "foo.github.io"},
+		{"a.b.foo.github.io.", "github.io", "foo.github.io"},
+		{"WWW.Example.COM", "com", "example.com"},
+		{"shop.example.ck", "example.ck", "shop.example.ck"},
+		{"example.ck", "example.ck", ""},
+		{"www.ck", "ck", "www.ck"},
+		{"a.www.ck", "ck", "www.ck"},
+		{"example.test", "test", "example.test"}, // Unlisted TLD
+		{"test", "test", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := list.PublicSuffix(tt.domain); got != tt.suffix {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", tt.domain, got, tt.suffix)
+		}
+		if got := list.RegistrableDomain(tt.domain); got != tt.registrable {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.domain, got, tt.registrable)
+		}
+	}
+}
+
+func TestRegistrableDomainEmbeddedList(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"www.example.com", "example.com"},
+		{"www.example.co.uk", "example.co.uk"},
+		{"foo.github.io", "foo.github.io"},
+		{"a.b.city.kawasaki.jp", "city.kawasaki.jp"},
+		{"a.b.example.kawasaki.jp", "b.example.kawasaki.jp"},
+		{"com", ""},
+	}
+
+	for _, tt := range tests {
+		if got := RegistrableDomain(tt.domain); got != tt.want {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package query
+
+import (
+	"dns_query_utility/result"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// newRecord converts a resource record to the typed result model
+func newRecord(rr dns.RR) result.Record {
+	if opt, ok := rr.(*dns.OPT); ok {
+		return newOPTRecord(opt)
+	}
+
+	hdr := rr.Header()
+	record := result.Record{
+		Name:  hdr.Name,
+		Type:  dns.TypeToString[hdr.Rrtype],
+		Class: dns.ClassToString[hdr.Class],
+		TTL:   hdr.Ttl,
+		Data:  strings.TrimPrefix(rr.String(), hdr.String()),
+	}
+	if record.Type == "" {
+		record.Type = dns.Type(hdr.Rrtype).String()
+	}
+	if record.Class == "" {
+		record.Class = dns.Class(hdr.Class).String()
+	}
+
+	switch r := rr.(type) {
+	case *dns.A:
+		record.Address = r.A.String()
+	case *dns.AAAA:
+		record.Address = r.AAAA.String()
+	case *dns.CNAME:
+		record.Target = r.Target
+	case *dns.DNAME:
+		record.Target = r.Target
+	case *dns.NS:
+		record.Target = r.Ns
+	case *dns.PTR:
+		record.Target = r.Ptr
+	case *dns.TXT:
+		record.Text = r.Txt
+	case *dns.MX:
+		record.MX = &result.MXData{Preference: r.Preference, Exchange: r.Mx}
+	case *dns.SRV:
+		record.SRV = &result.SRVData{Priority: r.Priority, Weight: r.Weight, Port: r.Port, Target: r.Target}
+	case *dns.SOA:
+		record.SOA = &result.SOAData{
+			MName:   r.Ns,
+			RName:   r.Mbox,
+			Serial:  r.Serial,
+			Refresh: r.Refresh,
+			Retry:   r.Retry,
+			Expire:  r.Expire,
+			Minimum: r.Minttl,
+		}
+	case *dns.CAA:
+		record.CAA = &result.CAAData{Flag: r.Flag, Tag: r.Tag, Value: r.Value}
+	case *dns.DNSKEY:
+		record.DNSKEY = &result.DNSKEYData{
+			Flags:     r.Flags,
+			Protocol:  r.Protocol,
+			Algorithm: r.Algorithm,
+			KeyTag:    r.KeyTag(),
+			PublicKey: r.PublicKey,
+		}
+	case *dns.DS:
+		record.DS = &result.DSData{KeyTag: r.KeyTag, Algorithm: r.Algorithm, DigestType: r.DigestType, Digest: strings.ToLower(r.Digest)}
+	case *dns.HTTPS:
+		record.SVCB = newSVCBData(&r.SVCB)
+	case *dns.SVCB:
+		record.SVCB = newSVCBData(r)
+	case *dns.TLSA:
+		record.TLSA = &result.TLSAData{
+			Usage:        r.Usage,
+			Selector:     r.Selector,
+			MatchingType: r.MatchingType,
+			Certificate:  strings.ToLower(r.Certificate),
+		}
+	case *dns.NAPTR:
+		record.NAPTR = &result.NAPTRData{
+			Order:       r.Order,
+			Preference:  r.Preference,
+			Flags:       r.Flags,
+			Service:     r.Service,
+			Regexp:      r.Regexp,
+			Replacement: r.Replacement,
+		}
+	case *dns.SSHFP:
+		record.SSHFP = &result.SSHFPData{Algorithm: r.Algorithm, Type: r.Type, Fingerprint: strings.ToLower(r.FingerPrint)}
+	}
+
+	return record
+}
+
+// newSVCBData converts the priority, target and parameters of an SVCB/HTTPS record
+func newSVCBData(rr *dns.SVCB) *result.SVCBData {
+	data := &result.SVCBData{Priority: rr.Priority, Target: rr.Target}
+	if len(rr.Value) > 0 {
+		data.Params = make(map[string]string, len(rr.Value))
+		for _, kv := range rr.Value {
+			data.Params[kv.Key().String()] = kv.String()
+		}
+	}
+	return data
+}
+
+// newOPTRecord converts the EDNS OPT pseudo-record, whose class and TTL
+// fields carry the UDP size and extended flags rather than their usual meaning
+func newOPTRecord(opt *dns.OPT) result.Record {
+	data := &result.OPTData{
+		UDPSize:       opt.UDPSize(),
+		Version:       opt.Version(),
+		DO:            opt.Do(),
+		ExtendedRcode: opt.ExtendedRcode(),
+	}
+	for _, option := range opt.Option {
+		data.Options = append(data.Options, result.EDNSOption{Code: option.Option(), Data: option.String()})
+	}
+
+	return result.Record{
+		Name: opt.Hdr.Name,
+		Type: "OPT",
+		TTL:  opt.Hdr.Ttl,
+		OPT:  data,
+	}
+}
+
+// newRecords converts resource records to the typed result model
+func newRecords(rrs []dns.RR) []result.Record {
+	var records []result.Record
+	for _, rr := range rrs {
+		records = append(records, newRecord(rr))
+	}
+	return records
+}
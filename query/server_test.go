@@ -0,0 +1,131 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestServer runs a miekg DNS server on a random local port, over both
+// UDP and TCP, that accepts every opcode, and returns its port
+func startTestServer(t *testing.T, handler dns.HandlerFunc) int {
+	t.Helper()
+	return startTSIGTestServer(t, handler, nil)
+}
+
+// startTSIGTestServer is startTestServer with TSIG keys: requests signed with
+// one of secrets are verified and their responses signed
+func startTSIGTestServer(t *testing.T, handler dns.HandlerFunc, secrets map[string]string) int {
+	t.Helper()
+
+	return serveTestPort(t, handler, secrets, "127.0.0.1")
+}
+
+// startLoopbackServers is startTestServer listening on more loopback
+// addresses, such as 127.0.0.2, all on the same port. The handler can tell
+// them apart by w.LocalAddr().
+func startLoopbackServers(t *testing.T, handler dns.HandlerFunc, hosts ...string) int {
+	t.Helper()
+	return serveTestPort(t, handler, nil, append([]string{"127.0.0.1"}, hosts...)...)
+}
+
+// serveTestPort serves handler over UDP and TCP on every host at one port
+func serveTestPort(t *testing.T, handler dns.HandlerFunc, secrets map[string]string, hosts ...string) int {
+	t.Helper()
+
+	conns, listeners, port := listenTestPort(t, hosts)
+
+	var servers []*dns.Server
+	for i := range hosts {
+		servers = append(servers, &dns.Server{PacketConn: conns[i]}, &dns.Server{Listener: listeners[i]})
+	}
+	for _, server := range servers {
+		started := make(chan struct{})
+		server.Handler = handler
+		server.TsigSecret = secrets
+		server.NotifyStartedFunc = func() { close(started) }
+		server.MsgAcceptFunc = func(dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept
+		}
+		go server.ActivateAndServe()
+		<-started
+		t.Cleanup(func() { server.Shutdown() })
+	}
+
+	return port
+}
+
+// listenTestPort opens UDP and TCP sockets on every host at the same random
+// port, trying again when the port is already taken on one of them. The
+// test is skipped when a host is not a local address, as 127.0.0.2 is not
+// on every system.
+func listenTestPort(t *testing.T, hosts []string) ([]net.PacketConn, []net.Listener, int) {
+	t.Helper()
+
+	var err error
+	for attempt := 0; attempt < 10; attempt++ {
+		var first net.PacketConn
+		first, err = net.ListenPacket("udp", net.JoinHostPort(hosts[0], "0"))
+		if err != nil {
+			break
+		}
+		port := strconv.Itoa(first.LocalAddr().(*net.UDPAddr).Port)
+
+		conns := []net.PacketConn{first}
+		var listeners []net.Listener
+		for i, host := range hosts {
+			if i > 0 {
+				var conn net.PacketConn
+				if conn, err = net.ListenPacket("udp", net.JoinHostPort(host, port)); err != nil {
+					break
+				}
+				conns = append(conns, conn)
+			}
+			var listener net.Listener
+			if listener, err = net.Listen("tcp", net.JoinHostPort(host, port)); err != nil {
+				break
+			}
+			listeners = append(listeners, listener)
+		}
+		if err == nil {
+			p, _ := strconv.Atoi(port)
+			return conns, listeners, p
+		}
+
+		for _, conn := range conns {
+			conn.Close()
+		}
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		if errors.Is(err, syscall.EADDRNOTAVAIL) {
+			t.Skipf("listen: %v", err)
+		}
+	}
+	t.Fatalf("listen: %v", err)
+	return nil, nil, 0
+}
+
+// testConfig returns a configuration that sends every query to the local
+// test server on port
+func testConfig(port int) config.Config {
+	return config.Config{
+		DNSServerIPv4: "127.0.0.1",
+		DNSPort:       port,
+		Timeout:       2 * time.Second,
+		WorkerCount:   1,
+		TCPFallback:   true,
+		EDNS:          config.DefaultEDNS(),
+	}
+}
+
+// testAddr joins the loopback address and port
+func testAddr(port int) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
@@ -0,0 +1,33 @@
+package query
+
+import "dns_query_utility/result"
+
+// Session holds state reused across queries executed by one worker, such as
+// open DoQ connections, validated DNSSEC zone keys and authoritative NS sets. A Session must not be
+// shared between goroutines.
+type Session struct {
+	doqConns    map[string]*doqConn
+	dnssecZones map[string]*zoneKeys
+	authZones   map[string]*zoneServers
+	zoneHealth  map[string]*result.ZoneHealth
+	delegations map[string]*result.DelegationResult
+}
+
+// NewSession creates an empty Session
+func NewSession() *Session {
+	return &Session{
+		doqConns:    make(map[string]*doqConn),
+		dnssecZones: make(map[string]*zoneKeys),
+		authZones:   make(map[string]*zoneServers),
+		zoneHealth:  make(map[string]*result.ZoneHealth),
+		delegations: make(map[string]*result.DelegationResult),
+	}
+}
+
+// Close shuts down all connections held by the session
+func (s *Session) Close() {
+	for server, dc := range s.doqConns {
+		dc.close()
+		delete(s.doqConns, server)
+	}
+}
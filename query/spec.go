@@ -1,7 +1,10 @@
 package query
 
 import (
+	"dns_query_utility/config"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -35,12 +38,15 @@ func ParseIPVersion(s string) (IPVersion, error) {
 	}
 }
 
-// Transport represents UDP or TCP protocol
+// Transport represents the protocol used to carry the query
 type Transport int
 
 const (
 	UDP Transport = iota
 	TCP
+	DoT // DNS-over-TLS (RFC 7858)
+	DoH // DNS-over-HTTPS (RFC 8484)
+	DoQ // DNS-over-QUIC (RFC 9250)
 )
 
 func (t Transport) String() string {
@@ -49,6 +55,12 @@ func (t Transport) String() string {
 		return "udp"
 	case TCP:
 		return "tcp"
+	case DoT:
+		return "dot"
+	case DoH:
+		return "doh"
+	case DoQ:
+		return "doq"
 	default:
 		return "unknown"
 	}
@@ -60,25 +72,58 @@ func ParseTransport(s string) (Transport, error) {
 		return UDP, nil
 	case "tcp":
 		return TCP, nil
+	case "dot":
+		return DoT, nil
+	case "doh":
+		return DoH, nil
+	case "doq":
+		return DoQ, nil
 	default:
-		return 0, errors.New("invalid transport: must be 'udp' or 'tcp'")
+		return 0, errors.New("invalid transport: must be 'udp', 'tcp', 'dot', 'doh' or 'doq'")
 	}
 }
 
+// Network returns the dns.Client network name for this transport over the given IP family
+func (t Transport) Network(ip IPVersion) string {
+	network := "udp"
+	if t == TCP || t == DoT || t == DoH {
+		network = "tcp"
+	}
+
+	if ip == IPv6 {
+		network += "6"
+	}
+	if t == DoT {
+		network += "-tls"
+	}
+
+	return network
+}
+
 // QueryType represents DNS query type (QTYPE) as a uint16 wire value
 type QueryType int
 
 const (
-	QueryTypeA     QueryType = 1
-	QueryTypeAAAA  QueryType = 28
-	QueryTypeMX    QueryType = 15
-	QueryTypeTXT   QueryType = 16
-	QueryTypeNS    QueryType = 2
-	QueryTypeSOA   QueryType = 6
-	QueryTypeCNAME QueryType = 5
-	QueryTypePTR   QueryType = 12
-	QueryTypeSRV   QueryType = 33
-	QueryTypeANY QueryType = 255
+	QueryTypeA      QueryType = 1
+	QueryTypeAAAA   QueryType = 28
+	QueryTypeMX     QueryType = 15
+	QueryTypeTXT    QueryType = 16
+	QueryTypeNS     QueryType = 2
+	QueryTypeSOA    QueryType = 6
+	QueryTypeCNAME  QueryType = 5
+	QueryTypePTR    QueryType = 12
+	QueryTypeSRV    QueryType = 33
+	QueryTypeNAPTR  QueryType = 35
+	QueryTypeDS     QueryType = 43
+	QueryTypeSSHFP  QueryType = 44
+	QueryTypeDNSKEY QueryType = 48
+	QueryTypeTLSA   QueryType = 52
+	QueryTypeSVCB   QueryType = 64
+	QueryTypeHTTPS  QueryType = 65
+	QueryTypeIXFR   QueryType = 251
+	QueryTypeAXFR   QueryType = 252
+	QueryTypeANY    QueryType = 255
+	QueryTypeCAA    QueryType = 257
 )
 
 // String returns canonical name for QueryType
@@ -102,52 +147,196 @@ func (qt QueryType) String() string {
 		return "TXT"
 	case QueryTypeSRV:
 		return "SRV"
+	case QueryTypeNAPTR:
+		return "NAPTR"
+	case QueryTypeDS:
+		return "DS"
+	case QueryTypeSSHFP:
+		return "SSHFP"
+	case QueryTypeDNSKEY:
+		return "DNSKEY"
+	case QueryTypeTLSA:
+		return "TLSA"
+	case QueryTypeSVCB:
+		return "SVCB"
+	case QueryTypeHTTPS:
+		return "HTTPS"
+	case QueryTypeCAA:
+		return "CAA"
+	case QueryTypeIXFR:
+		return "IXFR"
+	case QueryTypeAXFR:
+		return "AXFR"
 	case QueryTypeANY:
 		return "ANY"
 	default:
-		return "UNKNOWN"
+		// RFC 3597 generic notation for types without a mnemonic
+		return fmt.Sprintf("TYPE%d", int(qt))
 	}
 }
 
-// ParseQueryType converts string to QueryType (case-insensitive)
-func ParseQueryType(s string) QueryType {
-	switch strings.ToUpper(strings.TrimSpace(s)) {
+// ParseQueryType converts string to QueryType (case-insensitive). Besides the
+// named types it accepts RFC 3597 notation ("TYPE65") for any type code.
+func ParseQueryType(s string) (QueryType, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+
+	if code, ok := strings.CutPrefix(name, "TYPE"); ok && code != "" {
+		value, err := strconv.ParseUint(code, 10, 16)
+		if err != nil || value == 0 {
+			return 0, fmt.Errorf("invalid query type '%s': type code must be 1-65535", s)
+		}
+		return QueryType(value), nil
+	}
+
+	switch name {
 	case "A":
-		return QueryTypeA
+		return QueryTypeA, nil
 	case "AAAA":
-		return QueryTypeAAAA
+		return QueryTypeAAAA, nil
 	case "MX":
-		return QueryTypeMX
+		return QueryTypeMX, nil
 	case "TXT":
-		return QueryTypeTXT
+		return QueryTypeTXT, nil
 	case "NS":
-		return QueryTypeNS
+		return QueryTypeNS, nil
 	case "SOA":
-		return QueryTypeSOA
+		return QueryTypeSOA, nil
 	case "CNAME":
-		return QueryTypeCNAME
+		return QueryTypeCNAME, nil
 	case "PTR":
-		return QueryTypePTR
+		return QueryTypePTR, nil
 	case "SRV":
-		return QueryTypeSRV
+		return QueryTypeSRV, nil
+	case "NAPTR":
+		return QueryTypeNAPTR, nil
+	case "DS":
+		return QueryTypeDS, nil
+	case "SSHFP":
+		return QueryTypeSSHFP, nil
+	case "DNSKEY":
+		return QueryTypeDNSKEY, nil
+	case "TLSA":
+		return QueryTypeTLSA, nil
+	case "SVCB":
+		return QueryTypeSVCB, nil
+	case "HTTPS":
+		return QueryTypeHTTPS, nil
+	case "CAA":
+		return QueryTypeCAA, nil
+	case "IXFR":
+		return QueryTypeIXFR, nil
+	case "AXFR":
+		return QueryTypeAXFR, nil
 	case "ANY":
-		return QueryTypeANY
+		return QueryTypeANY, nil
 	default:
-		return QueryTypeA // fallback or handle error upstream
+		return 0, fmt.Errorf("invalid query type '%s'", s)
 	}
 }
 
+// IsTransfer reports whether the query type is a zone transfer (AXFR or IXFR)
+func (qt QueryType) IsTransfer() bool {
+	return qt == QueryTypeAXFR || qt == QueryTypeIXFR
+}
+
 // WireValue returns the uint16 value used in DNS packets
 func (qt QueryType) WireValue() uint16 {
 	return uint16(qt)
 }
 
+// QueryClass represents the DNS class (QCLASS); the zero value means IN
+type QueryClass int
+
+const (
+	ClassIN QueryClass = 1 // Internet
+	ClassCH QueryClass = 3 // CHAOS, used for server identification (version.bind, id.server)
+	ClassHS QueryClass = 4 // Hesiod
+)
+
+func (qc QueryClass) String() string {
+	switch qc {
+	case 0, ClassIN:
+		return "IN"
+	case ClassCH:
+		return "CH"
+	case ClassHS:
+		return "HS"
+	default:
+		return fmt.Sprintf("CLASS%d", int(qc))
+	}
+}
+
+// ParseQueryClass converts a class name (IN, CH/CHAOS, HS/HESIOD) to its value
+func ParseQueryClass(s string) (QueryClass, error) {
+	switch strings.ToUpper(s) {
+	case "", "IN":
+		return ClassIN, nil
+	case "CH", "CHAOS":
+		return ClassCH, nil
+	case "HS", "HESIOD":
+		return ClassHS, nil
+	default:
+		return 0, fmt.Errorf("invalid query class '%s': must be IN, CH or HS", s)
+	}
+}
+
+// IsIN reports whether the class is IN, the only class the zone checks apply to
+func (qc QueryClass) IsIN() bool {
+	return qc == 0 || qc == ClassIN
+}
+
+// WireValue returns the uint16 value used in DNS packets
+func (qc QueryClass) WireValue() uint16 {
+	if qc == 0 {
+		return uint16(ClassIN)
+	}
+	return uint16(qc)
+}
+
 // QuerySpec defines a single DNS query with three independent dimensions
 type QuerySpec struct {
-	Domain    string    // Domain name to resolve (e.g., "google.com")
-	QueryType QueryType // DNS record type: A, AAAA, MX, TXT, etc.
-	Transport Transport // Protocol: UDP or TCP
-	IPVersion IPVersion // Network family: IPv4 or IPv6 (socket layer)
+	Domain    string       // Domain name to resolve (e.g., "google.com")
+	QueryType QueryType    // DNS record type: A, AAAA, MX, TXT, etc.
+	Transport Transport    // Protocol: UDP, TCP, DoT, DoH or DoQ
+	IPVersion IPVersion    // Network family: IPv4 or IPv6 (socket layer)
+	EDNS      EDNSOverride // Per-row EDNS settings from the CSV
+	Class     QueryClass   // DNS class: IN (default), CH or HS
+
+	// ClientSubnet is the ECS subnet for this query; empty inherits the global
+	// setting and "off" sends no ECS option
+	ClientSubnet string
+
+	// Serial is the SOA serial the client already has, for IXFR queries
+	Serial uint32
+
+	// TSIGKey names the key used to sign this query; empty uses the default
+	// key and "off" sends the query unsigned
+	TSIGKey string
+
+	// Update turns the query into a dynamic update (--update mode); Domain and
+	// QueryType are the owner name and type of the record being changed
+	Update *UpdateSpec
+}
+
+// EDNSOverride holds per-query EDNS settings; nil fields inherit the global configuration
+type EDNSOverride struct {
+	Enabled *bool
+	UDPSize *uint16
+	DO      *bool
+}
+
+// Resolve applies the override on top of the global EDNS options
+func (o EDNSOverride) Resolve(base config.EDNSOptions) config.EDNSOptions {
+	if o.Enabled != nil {
+		base.Enabled = *o.Enabled
+	}
+	if o.UDPSize != nil {
+		base.UDPSize = *o.UDPSize
+	}
+	if o.DO != nil {
+		base.DO = *o.DO
+	}
+	return base
 }
 
 func (q *QuerySpec) Validate() error {
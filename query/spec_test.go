@@ -0,0 +1,81 @@
+package query
+
+import "testing"
+
+func TestParseQueryType(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    QueryType
+		wantErr bool
+	}{
+		{input: "A", want: QueryTypeA},
+		{input: " mx ", want: QueryTypeMX},
+		{input: "TYPE65", want: QueryTypeHTTPS},
+		{input: "type65534", want: QueryType(65534)},
+		{input: "TYPE65535", want: QueryType(65535)},
+		{input: "TYPE0", wantErr: true},
+		{input: "TYPE65536", wantErr: true},
+		{input: "TYPE-1", wantErr: true},
+		{input: "TYPEA", wantErr: true},
+		{input: "TYPE", wantErr: true},
+		{input: "BOGUS", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseQueryType(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQueryType(%q) = %v, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQueryType(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestQueryTypeStringRoundTrip(t *testing.T) {
+	for _, qt := range []QueryType{QueryTypeA, QueryTypeSVCB, QueryType(65534)} {
+		got, err := ParseQueryType(qt.String())
+		if err != nil || got != qt {
+			t.Errorf("ParseQueryType(%q) = %v, %v; want %v", qt.String(), got, err, qt)
+		}
+	}
+}
+
+func TestParseQueryClass(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    QueryClass
+		name    string // String of the parsed class
+		wantErr bool
+	}{
+		{input: "", want: ClassIN, name: "IN"},
+		{input: "in", want: ClassIN, name: "IN"},
+		{input: "CH", want: ClassCH, name: "CH"},
+		{input: "chaos", want: ClassCH, name: "CH"},
+		{input: "HESIOD", want: ClassHS, name: "HS"},
+		{input: "NONE", wantErr: true},
+		{input: "CLASS3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseQueryClass(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQueryClass(%q) = %v, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want || got.String() != tt.name {
+			t.Errorf("ParseQueryClass(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+
+	// The zero value is IN, so specs built without a class stay unchanged
+	var zero QueryClass
+	if !zero.IsIN() || zero.WireValue() != 1 || zero.String() != "IN" {
+		t.Errorf("zero class = %s (%d), want IN", zero, zero.WireValue())
+	}
+}
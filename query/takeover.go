@@ -0,0 +1,252 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	_ "embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// embeddedTakeoverSignatures is the default signature list for --takeover-audit
+//
+//go:embed takeover_signatures.txt
+var embeddedTakeoverSignatures string
+
+// Signature conditions
+const (
+	signatureNXDomain = "nxdomain" // Finding only when the target does not resolve
+	signatureAlways   = "always"   // Finding whenever the target matches
+)
+
+// TakeoverSignature identifies CNAME targets at a provider where an alias can
+// be taken over by claiming the resource it points to
+type TakeoverSignature struct {
+	Pattern   string // Target name, or a glob when it contains "*"
+	Condition string // "nxdomain" or "always"
+	Provider  string
+}
+
+// matches reports whether name is covered by the signature's pattern
+func (sig TakeoverSignature) matches(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if !strings.Contains(sig.Pattern, "*") {
+		return name == sig.Pattern || strings.HasSuffix(name, "."+sig.Pattern)
+	}
+	ok, _ := path.Match(sig.Pattern, name)
+	return ok
+}
+
+// LoadTakeoverSignatures reads signatures from path, one "pattern condition
+// provider" line each. An empty path returns the embedded list.
+func LoadTakeoverSignatures(path string) ([]TakeoverSignature, error) {
+	source := embeddedTakeoverSignatures
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read takeover signatures: %w", err)
+		}
+		source = string(data)
+	}
+
+	signatures, err := ParseTakeoverSignatures(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("takeover signature file %s contains no signatures", path)
+	}
+	return signatures, nil
+}
+
+// ParseTakeoverSignatures parses signature lines; blank lines and lines
+// starting with "#" are ignored
+func ParseTakeoverSignatures(source string) ([]TakeoverSignature, error) {
+	var signatures []TakeoverSignature
+
+	for i, line := range strings.Split(source, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("takeover signatures line %d: expected pattern, condition and provider", i+1)
+		}
+
+		sig := TakeoverSignature{
+			Pattern:   strings.TrimSuffix(strings.ToLower(fields[0]), "."),
+			Condition: strings.ToLower(fields[1]),
+			Provider:  strings.Join(fields[2:], " "),
+		}
+		if sig.Condition != signatureNXDomain && sig.Condition != signatureAlways {
+			return nil, fmt.Errorf("takeover signatures line %d: condition must be '%s' or '%s', got '%s'",
+				i+1, signatureNXDomain, signatureAlways, fields[1])
+		}
+		if _, err := path.Match(sig.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("takeover signatures line %d: invalid pattern '%s'", i+1, fields[0])
+		}
+
+		signatures = append(signatures, sig)
+	}
+
+	return signatures, nil
+}
+
+// AuditTakeover checks the CNAME chain of each aliased name in results for
+// targets that do not resolve or that match a provider signature. Each name
+// is audited once, from the first result that recorded its chain.
+func AuditTakeover(results []result.QueryResult, signatures []TakeoverSignature, cfg config.Config) *result.TakeoverReport {
+	report := &result.TakeoverReport{
+		Signatures: len(signatures),
+		Findings:   []result.TakeoverFinding{},
+	}
+
+	audited := make(map[string]bool)
+	for _, res := range results {
+		if res.CNAMEChain == nil {
+			continue
+		}
+		domain := dns.CanonicalName(res.Domain)
+		if audited[domain] {
+			continue
+		}
+		audited[domain] = true
+		report.Checked++
+
+		if finding := auditChain(res, signatures, cfg); finding != nil {
+			report.Findings = append(report.Findings, *finding)
+		}
+	}
+
+	// Highest risk first
+	rank := map[result.TakeoverRisk]int{
+		result.TakeoverHigh:          0,
+		result.TakeoverMedium:        1,
+		result.TakeoverLow:           2,
+		result.TakeoverIndeterminate: 3,
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return rank[report.Findings[i].Risk] < rank[report.Findings[j].Risk]
+	})
+
+	return report
+}
+
+// auditChain rates the alias recorded in res, returning nil when its target
+// resolves and matches no signature that applies
+func auditChain(res result.QueryResult, signatures []TakeoverSignature, cfg config.Config) *result.TakeoverFinding {
+	chain := *res.CNAMEChain
+	chain.Hops = append([]result.CNAMEHop(nil), chain.Hops...)
+
+	// A loop never resolves, but nobody can claim a name inside it
+	if chain.Loop || chain.TooLong {
+		return nil
+	}
+
+	// Work out whether the end of the chain resolves. Only an address for the
+	// final name shows that it does: CNAME and ANY queries stop at the alias,
+	// and other types may be answered by NODATA.
+	rcode := dns.RcodeSuccess
+	switch {
+	case res.ResponseCode == dns.RcodeNameError || res.ResponseCode == dns.RcodeServerFailure:
+		rcode = res.ResponseCode
+	case hasAddress(res.Answers, chain.Final) || hasAddress(chain.Answers, chain.Final):
+	default:
+		rcode = lookupChainEnd(&chain, res, cfg)
+	}
+
+	var sig *TakeoverSignature
+	var target string
+	for _, hop := range chain.Hops {
+		for i := range signatures {
+			if signatures[i].matches(hop.Target) {
+				sig, target = &signatures[i], hop.Target
+				break
+			}
+		}
+		if sig != nil {
+			break
+		}
+	}
+
+	names := []string{chain.Hops[0].Name}
+	for _, hop := range chain.Hops {
+		names = append(names, hop.Target)
+	}
+	finding := &result.TakeoverFinding{Domain: res.Domain, Chain: names, Target: chain.Final}
+
+	switch {
+	case rcode == dns.RcodeServerFailure:
+		// A lame or broken delegation, which says nothing about whether the
+		// name can be claimed
+		finding.Risk = result.TakeoverIndeterminate
+		if sig != nil {
+			finding.Target = target
+			finding.Provider = sig.Provider
+		}
+		finding.Reason = fmt.Sprintf("%s could not be resolved (SERVFAIL), usually a lame or broken delegation; check it again",
+			chain.Final)
+	case rcode == dns.RcodeNameError && sig != nil:
+		finding.Risk = result.TakeoverHigh
+		finding.Target = target
+		finding.Provider = sig.Provider
+		finding.Reason = fmt.Sprintf("%s does not exist (NXDOMAIN) and is at %s, where the resource can be claimed again",
+			chain.Final, sig.Provider)
+	case rcode == dns.RcodeNameError:
+		finding.Risk = result.TakeoverMedium
+		finding.Reason = fmt.Sprintf("%s does not exist (NXDOMAIN); check whether its domain can be registered",
+			chain.Final)
+	case sig != nil && sig.Condition == signatureAlways:
+		finding.Risk = result.TakeoverLow
+		finding.Target = target
+		finding.Provider = sig.Provider
+		finding.Reason = fmt.Sprintf("%s serves %s whether or not the resource is claimed; check that it still exists",
+			sig.Provider, target)
+	default:
+		return nil
+	}
+
+	return finding
+}
+
+// hasAddress reports whether records hold an A or AAAA record owned by name
+func hasAddress(records []result.Record, name string) bool {
+	for _, record := range records {
+		if (record.Type == "A" || record.Type == "AAAA") && dns.CanonicalName(record.Name) == dns.CanonicalName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupChainEnd asks the resolver for the address of the final name of a
+// chain, extending the chain with any further aliases, and returns the
+// response code. A failed lookup counts as success, since it proves nothing
+// about the name.
+func lookupChainEnd(chain *result.CNAMEChain, res result.QueryResult, cfg config.Config) int {
+	ipv, err := ParseIPVersion(res.IPVersion)
+	if err != nil {
+		return dns.RcodeSuccess
+	}
+	host := cfg.DNSServerIPv4
+	if ipv == IPv6 {
+		host = cfg.DNSServerIPv6
+	}
+	transport, err := ParseTransport(res.Transport)
+	if err != nil {
+		transport = UDP
+	}
+	resolver := resolverAddress(host, transport, cfg)
+
+	resp, err := resolverLookup(dns.Fqdn(chain.Final), dns.TypeA, ipv, cfg, resolver)
+	if err != nil {
+		return dns.RcodeSuccess
+	}
+	extendChain(chain, resp.Answer, resolver, cnameDepth(cfg))
+	return resp.Rcode
+}
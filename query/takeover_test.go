@@ -0,0 +1,137 @@
+package query
+
+import (
+	"dns_query_utility/result"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseTakeoverSignatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []TakeoverSignature
+		wantErr bool
+	}{
+		{
+			name:   "comments and blank lines",
+			source: "# provider list\n\nAzureWebsites.NET. nxdomain Azure App Service\n*.s3-website*.amazonaws.com always AWS S3\n",
+			want: []TakeoverSignature{
+				{Pattern: "azurewebsites.net", Condition: "nxdomain", Provider: "Azure App Service"},
+				{Pattern: "*.s3-website*.amazonaws.com", Condition: "always", Provider: "AWS S3"},
+			},
+		},
+		{name: "missing provider", source: "github.io always", wantErr: true},
+		{name: "unknown condition", source: "github.io sometimes GitHub Pages", wantErr: true},
+		{name: "invalid glob", source: "[a.example.com nxdomain Example", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTakeoverSignatures(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d signatures, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("signature %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTakeoverSignatureMatches(t *testing.T) {
+	suffix := TakeoverSignature{Pattern: "azurewebsites.net"}
+	glob := TakeoverSignature{Pattern: "*.s3-website*.amazonaws.com"}
+
+	tests := []struct {
+		sig  TakeoverSignature
+		name string
+		want bool
+	}{
+		{suffix, "azurewebsites.net.", true},
+		{suffix, "Example-App.AzureWebsites.net.", true},
+		{suffix, "notazurewebsites.net.", false},
+		{suffix, "azurewebsites.net.example.com.", false},
+		{glob, "bucket.s3-website-eu-west-1.amazonaws.com.", true},
+		{glob, "bucket.s3.amazonaws.com.", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.sig.matches(tt.name); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.sig.Pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditTakeoverCNAMEQueries(t *testing.T) {
+	aliases := map[string]string{
+		"gone.example.com.":    "gone-app.azurewebsites.net.",
+		"live.example.com.":    "live-app.azurewebsites.net.",
+		"lame.example.com.":    "lame-app.azurewebsites.net.",
+		"expired.example.com.": "www.expired.example.",
+	}
+	port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case aliases[q.Name] != "":
+			rr, _ := dns.NewRR(q.Name + " 60 IN CNAME " + aliases[q.Name])
+			m.Answer = []dns.RR{rr}
+		case q.Name == "live-app.azurewebsites.net.":
+			rr, _ := dns.NewRR(q.Name + " 60 IN A 192.0.2.1")
+			m.Answer = []dns.RR{rr}
+		case q.Name == "lame-app.azurewebsites.net.":
+			m.Rcode = dns.RcodeServerFailure
+		default:
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+	cfg := testConfig(port)
+
+	var results []result.QueryResult
+	for _, domain := range []string{"gone.example.com", "live.example.com", "lame.example.com", "expired.example.com"} {
+		spec := QuerySpec{Domain: domain, QueryType: QueryTypeCNAME, Transport: UDP, IPVersion: IPv4}
+		res := ExecuteQuery(spec, cfg)
+		if res.CNAMEChain == nil {
+			t.Fatalf("%s: no CNAME chain recorded (error: %s)", domain, res.Error)
+		}
+		results = append(results, res)
+	}
+
+	sigs := []TakeoverSignature{{Pattern: "azurewebsites.net", Condition: signatureNXDomain, Provider: "Azure App Service"}}
+	report := AuditTakeover(results, sigs, cfg)
+
+	if report.Checked != 4 {
+		t.Errorf("checked = %d, want 4", report.Checked)
+	}
+	want := map[string]result.TakeoverRisk{
+		"gone.example.com":    result.TakeoverHigh,
+		"expired.example.com": result.TakeoverMedium,
+		"lame.example.com":    result.TakeoverIndeterminate,
+	}
+	if len(report.Findings) != len(want) {
+		t.Fatalf("findings = %+v, want %d", report.Findings, len(want))
+	}
+	for _, finding := range report.Findings {
+		if finding.Risk != want[finding.Domain] {
+			t.Errorf("%s: risk = %q, want %q", finding.Domain, finding.Risk, want[finding.Domain])
+		}
+	}
+	if report.Findings[0].Domain != "gone.example.com" || report.Findings[2].Domain != "lame.example.com" {
+		t.Errorf("findings are not ordered by risk: %+v", report.Findings)
+	}
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"dns_query_utility/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// newTLSConfig builds the client TLS configuration for an encrypted transport.
+// When an SPKI pin is configured the chain is not checked against the system
+// roots; the pin must match the server's own (leaf) certificate and alone
+// authenticates the server (RFC 7858 out-of-band key-pinned profile), which
+// also allows testing against self-signed certificates.
+func newTLSConfig(opts config.TLSOptions, host string) (*tls.Config, error) {
+	serverName := opts.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if opts.SPKIPin == "" {
+		return tlsConfig, nil
+	}
+
+	pin, err := base64.StdEncoding.DecodeString(opts.SPKIPin)
+	if err != nil || len(pin) != sha256.Size {
+		return nil, fmt.Errorf("invalid SPKI pin '%s'", opts.SPKIPin)
+	}
+
+	// Only the leaf is pinned: the handshake proves the server holds its key,
+	// while any other certificate in the chain can be copied by anyone
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return fmt.Errorf("invalid server certificate: %w", err)
+		}
+		digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		if !bytes.Equal(digest[:], pin) {
+			return errors.New("server certificate does not match SPKI pin")
+		}
+		return nil
+	}
+
+	return tlsConfig, nil
+}
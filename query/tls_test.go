@@ -0,0 +1,149 @@
+package query
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"dns_query_utility/config"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// newTestCert creates a self-signed certificate for 127.0.0.1
+func newTestCert(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// spkiPin returns the base64 SHA-256 pin of a certificate's public key
+func spkiPin(t *testing.T, der []byte) string {
+	t.Helper()
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// startTLSServer accepts TLS connections presenting cert and completes the handshake
+func startTLSServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestSPKIPinMatchesLeafOnly(t *testing.T) {
+	leaf := newTestCert(t, "server")
+	pinned := newTestCert(t, "pinned-ca")
+
+	// The server holds the leaf key but appends a copy of the pinned certificate
+	chained := tls.Certificate{
+		Certificate: [][]byte{leaf.Certificate[0], pinned.Certificate[0]},
+		PrivateKey:  leaf.PrivateKey,
+	}
+	addr := startTLSServer(t, chained)
+
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{"leaf pin", spkiPin(t, leaf.Certificate[0]), false},
+		{"pin of a later certificate", spkiPin(t, pinned.Certificate[0]), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(config.TLSOptions{SPKIPin: tt.pin}, "127.0.0.1")
+			if err != nil {
+				t.Fatalf("newTLSConfig: %v", err)
+			}
+			conn, err := tls.Dial("tcp", addr, tlsConfig)
+			if conn != nil {
+				conn.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteQueryDoT(t *testing.T) {
+	cert := newTestCert(t, "dot")
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		Listener:          ln,
+		Net:               "tcp-tls",
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			rr, _ := dns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+			m.Answer = []dns.RR{rr}
+			w.WriteMsg(m)
+		}),
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	cfg := testConfig(ln.Addr().(*net.TCPAddr).Port)
+	cfg.TLSIPv4 = config.TLSOptions{SPKIPin: spkiPin(t, cert.Certificate[0])}
+
+	spec := QuerySpec{Domain: "example.com", QueryType: QueryTypeA, Transport: DoT, IPVersion: IPv4}
+	res := ExecuteQuery(spec, cfg)
+	if len(res.ResolvedIPs) != 1 || res.ResolvedIPs[0] != "192.0.2.1" {
+		t.Errorf("resolved %v (status %s, error %s), want [192.0.2.1]", res.ResolvedIPs, res.Status, res.Error)
+	}
+}
@@ -0,0 +1,370 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// rootHints lists the root servers (https://www.internic.net/domain/named.root)
+const rootHints = `
+.                    3600000 NS    a.root-servers.net.
+.                    3600000 NS    b.root-servers.net.
+.                    3600000 NS    c.root-servers.net.
+.                    3600000 NS    d.root-servers.net.
+.                    3600000 NS    e.root-servers.net.
+.                    3600000 NS    f.root-servers.net.
+.                    3600000 NS    g.root-servers.net.
+.                    3600000 NS    h.root-servers.net.
+.                    3600000 NS    i.root-servers.net.
+.                    3600000 NS    j.root-servers.net.
+.                    3600000 NS    k.root-servers.net.
+.                    3600000 NS    l.root-servers.net.
+.                    3600000 NS    m.root-servers.net.
+a.root-servers.net.  3600000 A     198.41.0.4
+a.root-servers.net.  3600000 AAAA  2001:503:ba3e::2:30
+b.root-servers.net.  3600000 A     170.247.170.2
+b.root-servers.net.  3600000 AAAA  2801:1b8:10::b
+c.root-servers.net.  3600000 A     192.33.4.12
+c.root-servers.net.  3600000 AAAA  2001:500:2::c
+d.root-servers.net.  3600000 A     199.7.91.13
+d.root-servers.net.  3600000 AAAA  2001:500:2d::d
+e.root-servers.net.  3600000 A     192.203.230.10
+e.root-servers.net.  3600000 AAAA  2001:500:a8::e
+f.root-servers.net.  3600000 A     192.5.5.241
+f.root-servers.net.  3600000 AAAA  2001:500:2f::f
+g.root-servers.net.  3600000 A     192.112.36.4
+g.root-servers.net.  3600000 AAAA  2001:500:12::d0d
+h.root-servers.net.  3600000 A     198.97.190.53
+h.root-servers.net.  3600000 AAAA  2001:500:1::53
+i.root-servers.net.  3600000 A     192.36.148.17
+i.root-servers.net.  3600000 AAAA  2001:7fe::53
+j.root-servers.net.  3600000 A     192.58.128.30
+j.root-servers.net.  3600000 AAAA  2001:503:c27::2:30
+k.root-servers.net.  3600000 A     193.0.14.129
+k.root-servers.net.  3600000 AAAA  2001:7fd::1
+l.root-servers.net.  3600000 A     199.7.83.42
+l.root-servers.net.  3600000 AAAA  2001:500:9f::42
+m.root-servers.net.  3600000 A     202.12.27.33
+m.root-servers.net.  3600000 AAAA  2001:dc3::35
+`
+
+// maxTraceSteps bounds the number of referrals followed for one query
+const maxTraceSteps = 32
+
+// maxTraceDepth bounds nested lookups of nameserver addresses missing glue
+const maxTraceDepth = 4
+
+// RootHints lists the root nameservers with their A and AAAA records
+type RootHints struct {
+	Servers []string
+	Addrs   map[string][]dns.RR
+}
+
+var (
+	rootHintsMu     sync.Mutex
+	rootHintsByFile = make(map[string]*RootHints)
+)
+
+// LoadRootHints reads root server NS, A and AAAA records in zone file format
+// (named.root) from path. An empty path returns the built-in hints.
+func LoadRootHints(path string) (*RootHints, error) {
+	rootHintsMu.Lock()
+	defer rootHintsMu.Unlock()
+
+	if hints, ok := rootHintsByFile[path]; ok {
+		return hints, nil
+	}
+
+	source := rootHints
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root hints file: %w", err)
+		}
+		source = string(data)
+	}
+
+	hints := &RootHints{Addrs: make(map[string][]dns.RR)}
+	parser := dns.NewZoneParser(strings.NewReader(source), ".", path)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		switch record := rr.(type) {
+		case *dns.NS:
+			if record.Hdr.Name == "." {
+				hints.Servers = append(hints.Servers, dns.CanonicalName(record.Ns))
+			}
+		case *dns.A, *dns.AAAA:
+			owner := dns.CanonicalName(rr.Header().Name)
+			hints.Addrs[owner] = append(hints.Addrs[owner], rr)
+		}
+	}
+	if err := parser.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse root hints: %w", err)
+	}
+	if len(hints.Servers) == 0 {
+		return nil, errors.New("root hints contain no NS records for the root zone")
+	}
+
+	rootHintsByFile[path] = hints
+	return hints, nil
+}
+
+// traceServer is a nameserver address to query during iterative resolution
+type traceServer struct {
+	name    string
+	address string
+}
+
+// tracer resolves a query by following referrals down from the root servers
+type tracer struct {
+	cfg     config.Config
+	hints   *RootHints
+	ipv     IPVersion
+	network string
+	steps   []result.TraceStep
+}
+
+// traceQuery resolves msg iteratively starting at the root hints, like
+// dig +trace. Every server queried is recorded as a step, including those
+// that failed. Queries use plain DNS over UDP (TCP for tcp rows and truncated
+// answers) since authoritative servers do not offer encrypted transports.
+func traceQuery(msg *dns.Msg, spec QuerySpec, cfg config.Config) (*dns.Msg, []result.TraceStep, error) {
+	hints, err := LoadRootHints(cfg.RootHintsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	network := UDP.Network(spec.IPVersion)
+	if spec.Transport == TCP {
+		network = TCP.Network(spec.IPVersion)
+	}
+
+	t := &tracer{
+		cfg:     cfg,
+		hints:   hints,
+		ipv:     spec.IPVersion,
+		network: network,
+	}
+
+	query := msg.Copy()
+	query.RecursionDesired = false
+
+	response, err := t.resolve(query, 0)
+	return response, t.steps, err
+}
+
+// resolve follows referrals for query until a server answers authoritatively.
+// Steps are only recorded for the top-level query (depth 0).
+func (t *tracer) resolve(query *dns.Msg, depth int) (*dns.Msg, error) {
+	qname := dns.CanonicalName(query.Question[0].Name)
+	zone := "."
+	servers := t.rootServers()
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("root hints contain no %s addresses", t.ipv)
+	}
+
+	for step := 0; step < maxTraceSteps; step++ {
+		response, stepIndex, err := t.exchange(query, zone, servers, depth)
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Rcode != dns.RcodeSuccess || response.Authoritative || len(response.Answer) > 0 {
+			return response, nil
+		}
+
+		child, nsNames := referral(response)
+		if child == "" {
+			// Neither an answer nor a delegation; report what the server said
+			return response, nil
+		}
+		if !dns.IsSubDomain(zone, child) || child == zone || !dns.IsSubDomain(child, qname) {
+			return nil, fmt.Errorf("invalid referral from %s zone to %s", zone, child)
+		}
+
+		if stepIndex >= 0 {
+			t.steps[stepIndex].Referral = child
+			t.steps[stepIndex].Nameservers = nsNames
+		}
+
+		servers = t.referralServers(response, nsNames, depth)
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("no reachable %s nameservers for %s", t.ipv, child)
+		}
+		zone = child
+	}
+
+	return nil, fmt.Errorf("gave up after %d referrals", maxTraceSteps)
+}
+
+// exchange sends query to each server in turn until one responds. It returns
+// the response and the index of its recorded step (-1 when not recording).
+func (t *tracer) exchange(query *dns.Msg, zone string, servers []traceServer, depth int) (*dns.Msg, int, error) {
+	var lastErr error
+
+	for _, server := range servers {
+		startTime := time.Now()
+		response, err := t.send(query, server.address)
+		latency := float64(time.Since(startTime).Nanoseconds()) / 1e6
+
+		stepIndex := -1
+		if depth == 0 {
+			step := result.TraceStep{
+				Zone:      zone,
+				Server:    server.name,
+				Address:   server.address,
+				LatencyMs: latency,
+			}
+			if err != nil {
+				step.Error = err.Error()
+			} else {
+				step.Rcode = dns.RcodeToString[response.Rcode]
+				step.Authoritative = response.Authoritative
+			}
+			t.steps = append(t.steps, step)
+			stepIndex = len(t.steps) - 1
+		}
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return response, stepIndex, nil
+	}
+
+	return nil, -1, fmt.Errorf("all nameservers for %s failed: %w", zone, lastErr)
+}
+
+// send performs one exchange, repeating a truncated UDP answer over TCP
+func (t *tracer) send(query *dns.Msg, address string) (*dns.Msg, error) {
+	client := &dns.Client{
+		Net:     t.network,
+		Timeout: t.cfg.Timeout,
+	}
+
+	response, _, err := client.Exchange(query, address)
+	if err == nil && response.Truncated && client.Net != TCP.Network(t.ipv) {
+		client.Net = TCP.Network(t.ipv)
+		response, _, err = client.Exchange(query, address)
+	}
+	return response, err
+}
+
+// rootServers returns the root hint addresses for the tracer's IP version
+func (t *tracer) rootServers() []traceServer {
+	var servers []traceServer
+	for _, name := range t.hints.Servers {
+		for _, addr := range t.addresses(t.hints.Addrs[name]) {
+			servers = append(servers, traceServer{name: name, address: addr})
+		}
+	}
+	return servers
+}
+
+// referralServers returns the addresses of the delegated nameservers, taken
+// from glue records or, when no glue is present, looked up from the root
+func (t *tracer) referralServers(response *dns.Msg, nsNames []string, depth int) []traceServer {
+	glue := make(map[string][]dns.RR)
+	for _, rr := range response.Extra {
+		owner := dns.CanonicalName(rr.Header().Name)
+		glue[owner] = append(glue[owner], rr)
+	}
+
+	var servers []traceServer
+	for _, name := range nsNames {
+		for _, addr := range t.addresses(glue[name]) {
+			servers = append(servers, traceServer{name: name, address: addr})
+		}
+	}
+	if len(servers) > 0 || depth >= maxTraceDepth {
+		return servers
+	}
+
+	// Glueless delegation: resolve nameserver names until one has an address
+	qtype := dns.TypeA
+	if t.ipv == IPv6 {
+		qtype = dns.TypeAAAA
+	}
+	for _, name := range nsNames {
+		lookup := new(dns.Msg)
+		lookup.SetQuestion(name, qtype)
+		lookup.RecursionDesired = false
+
+		response, err := t.resolve(lookup, depth+1)
+		if err != nil || response.Rcode != dns.RcodeSuccess {
+			continue
+		}
+		for _, addr := range t.addresses(response.Answer) {
+			servers = append(servers, traceServer{name: name, address: addr})
+		}
+		if len(servers) > 0 {
+			break
+		}
+	}
+	return servers
+}
+
+// addresses returns host:port strings for the A or AAAA records in rrs that
+// match the tracer's IP version
+func (t *tracer) addresses(rrs []dns.RR) []string {
+	port := nameserverPort(t.cfg)
+
+	var addrs []string
+	for _, rr := range rrs {
+		switch record := rr.(type) {
+		case *dns.A:
+			if t.ipv == IPv4 {
+				addrs = append(addrs, net.JoinHostPort(record.A.String(), port))
+			}
+		case *dns.AAAA:
+			if t.ipv == IPv6 {
+				addrs = append(addrs, net.JoinHostPort(record.AAAA.String(), port))
+			}
+		}
+	}
+	return addrs
+}
+
+// referral returns the delegated zone and its sorted NS names from the
+// authority section of a non-authoritative response
+func referral(response *dns.Msg) (string, []string) {
+	var zone string
+	var names []string
+
+	for _, rr := range response.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := dns.CanonicalName(ns.Hdr.Name)
+		if zone == "" {
+			zone = owner
+		}
+		if owner == zone {
+			names = append(names, dns.CanonicalName(ns.Ns))
+		}
+	}
+
+	sort.Strings(names)
+	return zone, names
+}
+
+// nameserverPort returns the port of servers found through root hints and NS
+// records. Real nameservers listen on 53; --nameserver-port only exists for
+// test hierarchies running on another port.
+func nameserverPort(cfg config.Config) string {
+	if cfg.NameserverPort == 0 {
+		return strconv.Itoa(config.DefaultDNSPort)
+	}
+	return strconv.Itoa(cfg.NameserverPort)
+}
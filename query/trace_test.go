@@ -0,0 +1,73 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNameserverPort(t *testing.T) {
+	tests := []struct {
+		cfg  config.Config
+		want string
+	}{
+		{config.Config{DNSPort: 5353}, "53"},
+		{config.Config{DNSPort: 5353, NameserverPort: 5360}, "5360"},
+	}
+
+	for _, tt := range tests {
+		if got := nameserverPort(tt.cfg); got != tt.want {
+			t.Errorf("nameserverPort(%+v) = %s, want %s", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestTraceQueryFollowsReferral(t *testing.T) {
+	// The same server plays the root and then the zone's nameserver
+	var queries atomic.Int32
+	port := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if queries.Add(1) == 1 {
+			m.Ns = []dns.RR{mustRR(t, "example. 3600 IN NS ns.example.")}
+			m.Extra = []dns.RR{mustRR(t, "ns.example. 3600 IN A 127.0.0.1")}
+		} else {
+			m.Authoritative = true
+			m.Answer = []dns.RR{mustRR(t, "www.example. 300 IN A 192.0.2.1")}
+		}
+		w.WriteMsg(m)
+	})
+
+	hints := filepath.Join(t.TempDir(), "test.root")
+	if err := os.WriteFile(hints, []byte(". 3600000 NS a.root.test.\na.root.test. 3600000 A 127.0.0.1\n"), 0o600); err != nil {
+		t.Fatalf("write root hints: %v", err)
+	}
+
+	// --dns points elsewhere; only the nameserver port reaches the hierarchy
+	cfg := testConfig(1)
+	cfg.RootHintsFile = hints
+	cfg.NameserverPort = port
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("www.example.", dns.TypeA)
+	spec := QuerySpec{Domain: "www.example", QueryType: QueryTypeA, Transport: UDP, IPVersion: IPv4}
+	resp, steps, err := traceQuery(msg, spec, cfg)
+	if err != nil {
+		t.Fatalf("trace failed: %v", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("answer = %v, want the A record", resp.Answer)
+	}
+	if len(steps) != 2 || steps[0].Referral != "example." || !steps[1].Authoritative {
+		t.Fatalf("steps = %+v, want a referral to example. and an authoritative answer", steps)
+	}
+	for _, step := range steps {
+		if step.Address != testAddr(port) {
+			t.Errorf("step sent to %s, want %s", step.Address, testAddr(port))
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// executeTransfer performs an AXFR or IXFR of spec.Domain from server. Transfers
+// stream over TCP (or TLS for dot rows, RFC 9103); udp rows are sent over TCP.
+// The transferred records are returned in zone file format.
+func executeTransfer(spec QuerySpec, cfg config.Config, res result.QueryResult, server string, host string, tlsOptions config.TLSOptions) result.QueryResult {
+	startTime := res.Timestamp
+	zone := dns.Fqdn(spec.Domain)
+
+	msg := new(dns.Msg)
+	if spec.QueryType == QueryTypeIXFR {
+		msg.SetIxfr(zone, spec.Serial, ".", ".")
+	} else {
+		msg.SetAxfr(zone)
+	}
+
+	transfer := &dns.Transfer{
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	}
+
+	switch spec.Transport {
+	case UDP, TCP:
+		res.Transport = TCP.String()
+	case DoT:
+		tlsConfig, err := newTLSConfig(tlsOptions, host)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		transfer.TLS = tlsConfig
+	default:
+		res.Error = fmt.Sprintf("zone transfers are not supported over %s", spec.Transport)
+		return res
+	}
+
+	tsigKey, err := resolveTSIG(spec, cfg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	info := &result.TransferInfo{}
+	if tsigKey.Name != "" {
+		msg = signQuery(msg, tsigKey)
+		transfer.TsigSecret = tsigSecrets(cfg)
+		info.TSIGKey = tsigKey.Name
+	}
+
+	var records []dns.RR
+	envelopes, err := transfer.In(msg, server)
+	if err == nil {
+		for envelope := range envelopes {
+			if envelope.Error != nil {
+				err = envelope.Error
+				break
+			}
+			info.Envelopes++
+			records = append(records, envelope.RR...)
+		}
+	}
+
+	res.LatencyMs = float64(time.Since(startTime).Nanoseconds()) / 1e6
+	if tsigKey.Name != "" {
+		res.TSIG = transferTSIG(tsigKey, info.Envelopes, err)
+	}
+
+	if err != nil {
+		res.Status, res.ResponseCode, res.Error = transferError(err)
+		return res
+	}
+	if len(records) == 0 {
+		res.Status = result.StatusNoAnswer
+		res.Error = "zone transfer returned no records"
+		return res
+	}
+
+	// An AXFR ends with a repeat of the SOA record, which does not belong in the zone.
+	// Servers may answer an IXFR with a full AXFR-style zone, recognizable by the
+	// second record not being an SOA.
+	if len(records) > 1 && (spec.QueryType == QueryTypeAXFR || !isSOA(records[1])) {
+		if _, ok := records[len(records)-1].(*dns.SOA); ok {
+			records = records[:len(records)-1]
+		}
+	}
+
+	res.Transfer = info
+	info.RecordCount = len(records)
+	res.Answers = newRecords(records)
+	res.Records = make([]string, 0, len(records))
+	for _, rr := range records {
+		res.Records = append(res.Records, rr.String())
+
+		switch record := rr.(type) {
+		case *dns.SOA:
+			if info.Serial == 0 {
+				info.Serial = record.Serial
+			}
+		case *dns.NS:
+			if dns.CanonicalName(record.Hdr.Name) == dns.CanonicalName(zone) {
+				res.AuthoritativeNS = append(res.AuthoritativeNS, record.Ns)
+			}
+		}
+	}
+	res.Status = result.StatusSuccess
+
+	if cfg.ZoneFileDir != "" {
+		path, err := writeZoneFile(cfg.ZoneFileDir, spec, res.Records)
+		if err != nil {
+			res.Status = result.StatusError
+			res.Error = err.Error()
+			return res
+		}
+		info.ZoneFile = path
+	}
+
+	return res
+}
+
+// isSOA reports whether rr is an SOA record
+func isSOA(rr dns.RR) bool {
+	_, ok := rr.(*dns.SOA)
+	return ok
+}
+
+// transferError maps a failed transfer to a status, response code and message.
+// miekg/dns reports a non-zero rcode in the first message as "bad xfr rcode".
+func transferError(err error) (result.QueryStatus, int, string) {
+	rcode, ok := transferRcode(err)
+	if !ok {
+		if isTSIGError(err) {
+			return result.StatusError, 0, fmt.Sprintf("TSIG verification failed: %v", err)
+		}
+		if strings.Contains(err.Error(), "timeout") {
+			return result.StatusTimeout, 0, err.Error()
+		}
+		return result.StatusError, 0, err.Error()
+	}
+
+	switch rcode {
+	case dns.RcodeRefused:
+		return result.StatusRefused, rcode, "zone transfer refused"
+	case dns.RcodeNotAuth:
+		return result.StatusRefused, rcode, "zone transfer not authorized (NOTAUTH)"
+	case dns.RcodeNameError:
+		return result.StatusNXDomain, rcode, "domain does not exist"
+	case dns.RcodeServerFailure:
+		return result.StatusServFail, rcode, "server failure"
+	default:
+		return result.StatusError, rcode, fmt.Sprintf("unexpected response code: %d", rcode)
+	}
+}
+
+// transferRcode extracts the response code of a transfer that failed with a
+// non-zero rcode
+func transferRcode(err error) (int, bool) {
+	var rcode int
+	if err == nil {
+		return 0, false
+	}
+	if _, scanErr := fmt.Sscanf(err.Error(), "dns: bad xfr rcode: %d", &rcode); scanErr != nil {
+		return 0, false
+	}
+	return rcode, true
+}
+
+// transferTSIG records how the responses to a transfer signed with key were
+// signed. dns.Transfer verifies the TSIG of every message before looking at
+// its rcode and fails on unsigned ones, so messages that were received
+// without a TSIG error were signed and verified.
+func transferTSIG(key config.TSIGKey, envelopes int, err error) *result.TSIGResult {
+	info := &result.TSIGResult{Key: key.Name, Algorithm: key.Algorithm}
+	_, rcodeErr := transferRcode(err)
+
+	switch {
+	case errors.Is(err, dns.ErrNoSig):
+		info.Error = "response is not signed"
+	case isTSIGError(err):
+		info.Signed = true
+		info.Error = err.Error()
+	case err == nil || rcodeErr || envelopes > 0:
+		info.Signed = true
+		info.Verified = true
+	}
+	return info
+}
+
+// writeZoneFile writes the transferred records to
+// <dir>/<zone>-<type>-<network>.zone and returns the path
+func writeZoneFile(dir string, spec QuerySpec, records []string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create zone file directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.zone", strings.TrimSuffix(strings.ToLower(spec.Domain), "."),
+		strings.ToLower(spec.QueryType.String()), spec.IPVersion)
+	path := filepath.Join(dir, name)
+
+	if err := os.WriteFile(path, []byte(strings.Join(records, "\n")+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write zone file: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,127 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// transferServer serves example. by AXFR, answering with two envelopes, and
+// refuses every other zone. Signed requests with a bad MAC are refused too.
+func transferServer(t *testing.T) dns.HandlerFunc {
+	soa := mustRR(t, "example. 3600 IN SOA ns.example. admin.example. 2024010101 7200 3600 1209600 300")
+	zone := []dns.RR{
+		soa,
+		mustRR(t, "example. 3600 IN NS ns.example."),
+		mustRR(t, "ns.example. 3600 IN A 192.0.2.53"),
+		mustRR(t, "www.example. 300 IN A 192.0.2.1"),
+	}
+
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		q := r.Question[0]
+		if q.Name != "example." || q.Qtype != dns.TypeAXFR || (r.IsTsig() != nil && w.TsigStatus() != nil) {
+			m := new(dns.Msg)
+			m.SetRcode(r, dns.RcodeRefused)
+			if t := r.IsTsig(); t != nil && w.TsigStatus() == nil {
+				m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+			}
+			w.WriteMsg(m)
+			return
+		}
+
+		ch := make(chan *dns.Envelope, 2)
+		ch <- &dns.Envelope{RR: zone[:2]}
+		ch <- &dns.Envelope{RR: append(zone[2:], soa)}
+		close(ch)
+		if err := new(dns.Transfer).Out(w, r, ch); err != nil {
+			t.Errorf("transfer out: %v", err)
+		}
+		w.Hijack()
+	}
+}
+
+func TestExecuteTransfer(t *testing.T) {
+	key := config.TSIGKey{Name: "xfr.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0LWtleQ=="}
+	wrongKey := config.TSIGKey{Name: "xfr.", Algorithm: dns.HmacSHA256, Secret: "d3Jvbmcta2V5"}
+
+	tests := []struct {
+		name       string
+		zone       string
+		key        config.TSIGKey
+		wantStatus result.QueryStatus
+		wantRcode  int
+		wantError  string
+		wantTSIG   string // "", "verified" or "failed"
+	}{
+		{name: "unsigned", zone: "example", wantStatus: result.StatusSuccess},
+		{name: "signed", zone: "example", key: key, wantStatus: result.StatusSuccess, wantTSIG: "verified"},
+		{name: "wrong secret", zone: "example", key: wrongKey, wantStatus: result.StatusError, wantError: "TSIG verification failed", wantTSIG: "failed"},
+		{name: "refused", zone: "other.example", wantStatus: result.StatusRefused, wantRcode: dns.RcodeRefused},
+		{name: "signed refusal", zone: "other.example", key: key, wantStatus: result.StatusRefused, wantRcode: dns.RcodeRefused, wantTSIG: "verified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := startTSIGTestServer(t, transferServer(t), map[string]string{key.Name: key.Secret})
+			cfg := testConfig(port)
+			cfg.TSIG = tt.key
+			cfg.ZoneFileDir = t.TempDir()
+
+			spec := QuerySpec{Domain: tt.zone, QueryType: QueryTypeAXFR, Transport: UDP, IPVersion: IPv4}
+			res := ExecuteQuery(spec, cfg)
+
+			if res.Status != tt.wantStatus || res.ResponseCode != tt.wantRcode {
+				t.Fatalf("status %s, rcode %d (error %q); want %s, %d",
+					res.Status, res.ResponseCode, res.Error, tt.wantStatus, tt.wantRcode)
+			}
+			switch tsig := res.TSIG; tt.wantTSIG {
+			case "":
+				if tsig != nil {
+					t.Errorf("tsig = %+v for an unsigned transfer", tsig)
+				}
+			case "verified":
+				if tsig == nil || tsig.Key != tt.key.Name || tsig.Algorithm != tt.key.Algorithm || !tsig.Signed || !tsig.Verified || tsig.Error != "" {
+					t.Errorf("tsig = %+v, want a verified signature with key %s", tsig, tt.key.Name)
+				}
+			case "failed":
+				if tsig == nil || tsig.Verified || tsig.Error == "" {
+					t.Errorf("tsig = %+v, want a failed verification", tsig)
+				}
+			}
+			if !strings.HasPrefix(res.Error, tt.wantError) {
+				t.Errorf("error = %q, want prefix %q", res.Error, tt.wantError)
+			}
+			if res.Transport != TCP.String() {
+				t.Errorf("transport = %s, want tcp", res.Transport)
+			}
+			if tt.wantStatus != result.StatusSuccess {
+				return
+			}
+
+			info := res.Transfer
+			if info == nil || info.Envelopes != 2 || info.RecordCount != 4 || info.Serial != 2024010101 || info.TSIGKey != tt.key.Name {
+				t.Fatalf("transfer = %+v, want 2 envelopes, 4 records, serial 2024010101, key %q", info, tt.key.Name)
+			}
+			if len(res.AuthoritativeNS) != 1 || res.AuthoritativeNS[0] != "ns.example." {
+				t.Errorf("authoritative NS = %v, want [ns.example.]", res.AuthoritativeNS)
+			}
+
+			if info.ZoneFile != filepath.Join(cfg.ZoneFileDir, "example-axfr-ipv4.zone") {
+				t.Errorf("zone file = %q", info.ZoneFile)
+			}
+			data, err := os.ReadFile(info.ZoneFile)
+			if err != nil {
+				t.Fatalf("read zone file: %v", err)
+			}
+			if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != len(res.Records) {
+				t.Errorf("zone file has %d lines, want %d:\n%s", len(lines), len(res.Records), data)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// tsigFudge is the allowed clock skew in seconds for signed messages
+const tsigFudge = 300
+
+// resolveTSIG returns the key that signs spec: the row's key if it names one,
+// otherwise the default key. An empty Name means the query is sent unsigned.
+func resolveTSIG(spec QuerySpec, cfg config.Config) (config.TSIGKey, error) {
+	switch {
+	case strings.EqualFold(spec.TSIGKey, "off"):
+		return config.TSIGKey{}, nil
+	case spec.TSIGKey == "":
+		return cfg.TSIG, nil
+	}
+
+	key, ok := cfg.LookupTSIGKey(spec.TSIGKey)
+	if !ok {
+		return config.TSIGKey{}, fmt.Errorf("unknown TSIG key '%s'", spec.TSIGKey)
+	}
+	return key, nil
+}
+
+// signQuery returns a copy of msg carrying a TSIG record for key. The MAC
+// itself is computed when the message is packed for sending.
+func signQuery(msg *dns.Msg, key config.TSIGKey) *dns.Msg {
+	signed := msg.Copy()
+	signed.SetTsig(key.Name, key.Algorithm, tsigFudge, time.Now().Unix())
+	return signed
+}
+
+// tsigSecrets maps every configured key name to its secret, as expected by
+// dns.Client and dns.Transfer
+func tsigSecrets(cfg config.Config) map[string]string {
+	secrets := make(map[string]string, len(cfg.TSIGKeys)+1)
+	for _, key := range cfg.TSIGKeys {
+		secrets[key.Name] = key.Secret
+	}
+	if cfg.TSIG.Name != "" {
+		secrets[cfg.TSIG.Name] = cfg.TSIG.Secret
+	}
+	return secrets
+}
+
+// packQuery packs msg for transports that do their own framing (DoH, DoQ),
+// signing it when it carries a TSIG record. The returned MAC is needed to
+// verify the response.
+func packQuery(msg *dns.Msg, cfg config.Config) ([]byte, string, error) {
+	t := msg.IsTsig()
+	if t == nil {
+		packed, err := msg.Pack()
+		return packed, "", err
+	}
+
+	secret, ok := tsigSecrets(cfg)[t.Hdr.Name]
+	if !ok {
+		return nil, "", dns.ErrSecret
+	}
+	return dns.TsigGenerate(msg, secret, "", false)
+}
+
+// unpackResponse unpacks a response read by a DoH or DoQ exchange and checks
+// its TSIG record against requestMAC. The message is returned together with a
+// verification error so the caller can still report the server's answer.
+func unpackResponse(body []byte, requestMAC string, cfg config.Config) (*dns.Msg, error) {
+	response := new(dns.Msg)
+	if err := response.Unpack(body); err != nil {
+		return nil, err
+	}
+
+	t := response.IsTsig()
+	if t == nil || requestMAC == "" {
+		return response, nil
+	}
+
+	secret, ok := tsigSecrets(cfg)[t.Hdr.Name]
+	if !ok {
+		return response, dns.ErrSecret
+	}
+	return response, dns.TsigVerify(body, secret, requestMAC, false)
+}
+
+// isTSIGError reports whether err is a signature verification failure rather
+// than a network problem worth retrying
+func isTSIGError(err error) bool {
+	return errors.Is(err, dns.ErrSig) || errors.Is(err, dns.ErrTime) ||
+		errors.Is(err, dns.ErrSecret) || errors.Is(err, dns.ErrKeyAlg) ||
+		errors.Is(err, dns.ErrNoSig)
+}
+
+// verifyTSIG records how the response to a query signed with key was signed.
+// A signed query only succeeds when the response carries a valid signature,
+// so a rejected or unsigned response is returned as an error.
+func verifyTSIG(response *dns.Msg, key config.TSIGKey, err error) (*result.TSIGResult, error) {
+	info := &result.TSIGResult{Key: key.Name, Algorithm: key.Algorithm}
+	if response == nil {
+		return info, err
+	}
+
+	t := response.IsTsig()
+	info.Signed = t != nil
+
+	switch {
+	case t != nil && t.Error != dns.RcodeSuccess:
+		info.Error = tsigErrorString(t.Error)
+		return info, fmt.Errorf("TSIG rejected by server: %s", info.Error)
+	case isTSIGError(err):
+		info.Error = err.Error()
+		return info, fmt.Errorf("TSIG verification failed: %w", err)
+	case err != nil:
+		return info, err
+	case t == nil:
+		info.Error = "response is not signed"
+		return info, errors.New("TSIG verification failed: response is not signed")
+	}
+
+	info.Verified = true
+	return info, nil
+}
+
+// tsigErrorString names a TSIG error code (RFC 8945 section 3)
+func tsigErrorString(code uint16) string {
+	if name, ok := dns.RcodeToString[int(code)]; ok {
+		return name
+	}
+	return fmt.Sprintf("error %d", code)
+}
@@ -0,0 +1,274 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// UpdateAction is the change a dynamic update (RFC 2136) makes to the zone
+type UpdateAction string
+
+const (
+	UpdateAdd     UpdateAction = "add"     // Add the record to its RRset
+	UpdateDelete  UpdateAction = "delete"  // Delete the record, its RRset ("name TYPE") or the name ("name ANY")
+	UpdateReplace UpdateAction = "replace" // Replace the whole RRset with the record
+)
+
+// UpdateSpec is one operation of --update mode
+type UpdateSpec struct {
+	Action UpdateAction
+	Record string // Record in zone file format
+	Zone   string // Zone to update; empty locates it by walking SOA queries
+
+	rr    dns.RR
+	rrset bool // Record gives only an owner name and type, without data
+}
+
+// ParseUpdate validates an update operation. Delete operations may leave out
+// the record data to delete a whole RRset, or use type ANY to delete every
+// record at the name.
+func ParseUpdate(action string, record string, zone string) (*UpdateSpec, error) {
+	u := &UpdateSpec{Action: UpdateAction(strings.ToLower(action)), Record: record}
+
+	switch u.Action {
+	case UpdateAdd, UpdateDelete, UpdateReplace:
+	default:
+		return nil, fmt.Errorf("invalid update action '%s': must be add, delete or replace", action)
+	}
+
+	rr, err := dns.NewRR(record)
+	if err != nil || rr == nil {
+		return nil, fmt.Errorf("invalid record '%s': %v", record, err)
+	}
+	u.rr = rr
+	u.rrset = !hasRdata(record, rr.Header().Rrtype)
+
+	if u.rrset && u.Action != UpdateDelete {
+		return nil, fmt.Errorf("record '%s' has no data to %s", record, u.Action)
+	}
+	if rr.Header().Rrtype == dns.TypeANY && !u.rrset {
+		return nil, fmt.Errorf("record '%s': type ANY is only valid without data", record)
+	}
+
+	if zone != "" {
+		u.Zone = dns.CanonicalName(zone)
+		if !dns.IsSubDomain(u.Zone, dns.CanonicalName(rr.Header().Name)) {
+			return nil, fmt.Errorf("record '%s' is not in zone '%s'", rr.Header().Name, zone)
+		}
+	}
+
+	return u, nil
+}
+
+// hasRdata reports whether anything follows the type field of a record in
+// zone file format
+func hasRdata(record string, rrtype uint16) bool {
+	fields := strings.Fields(record)
+	for i, field := range fields {
+		if strings.EqualFold(field, dns.TypeToString[rrtype]) || strings.EqualFold(field, fmt.Sprintf("TYPE%d", rrtype)) {
+			return i < len(fields)-1
+		}
+	}
+	return true
+}
+
+// Name returns the owner name of the record being changed
+func (u *UpdateSpec) Name() string {
+	return u.rr.Header().Name
+}
+
+// Type returns the type of the record being changed
+func (u *UpdateSpec) Type() QueryType {
+	return QueryType(u.rr.Header().Rrtype)
+}
+
+// executeUpdate sends spec.Update as a DNS UPDATE message to server and then
+// queries the server to verify that the change was applied
+func executeUpdate(spec QuerySpec, cfg config.Config, res result.QueryResult, server string, host string, tlsOptions config.TLSOptions) result.QueryResult {
+	startTime := res.Timestamp
+	u := spec.Update
+	info := &result.UpdateResult{Action: string(u.Action), Record: u.rr.String()}
+	if u.rrset {
+		// A record without data prints with a misleading default TTL
+		info.Record = u.Record
+	}
+	res.Update = info
+
+	client := &dns.Client{
+		Net:        spec.Transport.Network(spec.IPVersion),
+		Timeout:    cfg.Timeout,
+		TsigSecret: tsigSecrets(cfg),
+	}
+	switch spec.Transport {
+	case UDP, TCP:
+	case DoT:
+		tlsConfig, err := newTLSConfig(tlsOptions, host)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		client.TLSConfig = tlsConfig
+	default:
+		res.Error = fmt.Sprintf("dynamic updates are not supported over %s", spec.Transport)
+		return res
+	}
+
+	// Locate the zone through the server being updated
+	resolver := resolverAddress(host, spec.Transport, cfg)
+	zone := u.Zone
+	if zone == "" {
+		var err error
+		zone, err = findZoneCut(u.Name(), spec.IPVersion, cfg, resolver)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+	info.Zone = zone
+
+	// The update helpers rewrite the class and TTL of the records they are
+	// given, so hand them a copy and keep u.rr intact for verification
+	msg := new(dns.Msg)
+	msg.SetUpdate(zone)
+	rrs := []dns.RR{dns.Copy(u.rr)}
+	switch {
+	case u.Action == UpdateAdd:
+		msg.Insert(rrs)
+	case u.Action == UpdateReplace:
+		msg.RemoveRRset(rrs)
+		msg.Insert(rrs)
+	case u.rrset && u.rr.Header().Rrtype == dns.TypeANY:
+		msg.RemoveName(rrs)
+	case u.rrset:
+		msg.RemoveRRset(rrs)
+	default:
+		msg.Remove(rrs)
+	}
+
+	tsigKey, err := resolveTSIG(spec, cfg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if tsigKey.Name != "" {
+		msg = signQuery(msg, tsigKey)
+	}
+
+	// Add, delete and replace are idempotent, so retrying a lost update is safe
+	var response *dns.Msg
+	for attempt := 0; attempt <= cfg.RetryCount; attempt++ {
+		response, _, err = client.Exchange(msg, server)
+		if err == nil || isTSIGError(err) || attempt == cfg.RetryCount {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	res.LatencyMs = float64(time.Since(startTime).Nanoseconds()) / 1e6
+
+	if tsigKey.Name != "" {
+		res.TSIG, err = verifyTSIG(response, tsigKey, err)
+	}
+	if err != nil {
+		res.Error = err.Error()
+		if strings.Contains(err.Error(), "timeout") {
+			res.Status = result.StatusTimeout
+		}
+		return res
+	}
+
+	res.ResponseCode = response.Rcode
+	switch response.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeRefused, dns.RcodeNotAuth:
+		res.Status = result.StatusRefused
+		res.Error = fmt.Sprintf("update refused (%s)", dns.RcodeToString[response.Rcode])
+		return res
+	default:
+		res.Error = fmt.Sprintf("update failed: %s", rcodeString(response.Rcode))
+		return res
+	}
+
+	// Ask the same server for the record to confirm the change took effect
+	current, err := verifyUpdate(u, client, server, tsigKey)
+	if err != nil {
+		res.Error = fmt.Sprintf("update accepted but verification query failed: %v", err)
+		return res
+	}
+	for _, rr := range current {
+		info.Current = append(info.Current, rr.String())
+	}
+	info.Verified = updateApplied(u, current)
+	if !info.Verified {
+		res.Error = "update accepted but not visible on the server"
+		return res
+	}
+
+	res.Status = result.StatusSuccess
+	return res
+}
+
+// verifyUpdate queries server for the updated name and type and returns the
+// matching records of the answer
+func verifyUpdate(u *UpdateSpec, client *dns.Client, server string, tsigKey config.TSIGKey) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(u.Name()), u.rr.Header().Rrtype)
+	if tsigKey.Name != "" {
+		msg = signQuery(msg, tsigKey)
+	}
+
+	resp, _, err := client.Exchange(msg, server)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("server answered %s", rcodeString(resp.Rcode))
+	}
+
+	var current []dns.RR
+	for _, rr := range resp.Answer {
+		hdr := rr.Header()
+		if dns.CanonicalName(hdr.Name) != dns.CanonicalName(u.Name()) {
+			continue
+		}
+		if hdr.Rrtype == u.rr.Header().Rrtype || u.rr.Header().Rrtype == dns.TypeANY {
+			current = append(current, rr)
+		}
+	}
+	return current, nil
+}
+
+// updateApplied reports whether the records now served match the update
+func updateApplied(u *UpdateSpec, current []dns.RR) bool {
+	present := false
+	for _, rr := range current {
+		if dns.IsDuplicate(rr, u.rr) {
+			present = true
+		}
+	}
+
+	switch {
+	case u.Action == UpdateAdd:
+		return present
+	case u.Action == UpdateReplace:
+		return present && len(current) == 1
+	case u.rrset:
+		return len(current) == 0
+	default:
+		return !present
+	}
+}
+
+// rcodeString names a response code, falling back to its number
+func rcodeString(rcode int) string {
+	if name, ok := dns.RcodeToString[rcode]; ok {
+		return name
+	}
+	return strconv.Itoa(rcode)
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// updateServer serves a single zone and applies UPDATE messages only when
+// apply is set. With keepOld, deletions are ignored and only additions land.
+type updateServer struct {
+	mu      sync.Mutex
+	records []dns.RR
+	apply   bool
+	keepOld bool
+}
+
+func (s *updateServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	if r.Opcode == dns.OpcodeUpdate {
+		if s.apply {
+			for _, rr := range r.Ns {
+				switch rr.Header().Class {
+				case dns.ClassNONE, dns.ClassANY:
+					if !s.keepOld {
+						s.remove(rr)
+					}
+				case dns.ClassINET:
+					s.records = append(s.records, rr)
+				}
+			}
+		}
+		w.WriteMsg(m)
+		return
+	}
+
+	q := r.Question[0]
+	for _, rr := range s.records {
+		if dns.CanonicalName(rr.Header().Name) == dns.CanonicalName(q.Name) && (rr.Header().Rrtype == q.Qtype || q.Qtype == dns.TypeANY) {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	w.WriteMsg(m)
+}
+
+// remove applies one deletion from the update section (RFC 2136 section 2.5):
+// class NONE deletes a record, class ANY an RRset, or the name with type ANY
+func (s *updateServer) remove(del dns.RR) {
+	hdr := del.Header()
+	kept := s.records[:0]
+	for _, cur := range s.records {
+		drop := false
+		if dns.CanonicalName(cur.Header().Name) == dns.CanonicalName(hdr.Name) {
+			switch {
+			case hdr.Class == dns.ClassANY:
+				drop = hdr.Rrtype == dns.TypeANY || hdr.Rrtype == cur.Header().Rrtype
+			default:
+				rr := dns.Copy(del)
+				rr.Header().Class = dns.ClassINET
+				rr.Header().Ttl = cur.Header().Ttl
+				drop = dns.IsDuplicate(cur, rr)
+			}
+		}
+		if !drop {
+			kept = append(kept, cur)
+		}
+	}
+	s.records = kept
+}
+
+func TestExecuteUpdateVerifies(t *testing.T) {
+	zone := []string{
+		"www.example.com. 300 IN A 192.0.2.10",
+		"www.example.com. 300 IN A 192.0.2.11",
+		"www.example.com. 300 IN TXT \"v=1\"",
+	}
+
+	tests := []struct {
+		name     string
+		action   string
+		record   string
+		apply    bool
+		keepOld  bool
+		verified bool
+		current  int // Records the verification query finds afterwards
+	}{
+		{name: "delete applied", action: "delete", record: "www.example.com. 300 IN A 192.0.2.10", apply: true, verified: true, current: 1},
+		{name: "delete ignored by server", action: "delete", record: "www.example.com. 300 IN A 192.0.2.10", current: 2},
+		{name: "add applied", action: "add", record: "www.example.com. 300 IN A 192.0.2.12", apply: true, verified: true, current: 3},
+		{name: "add ignored by server", action: "add", record: "www.example.com. 300 IN A 192.0.2.12", current: 2},
+		{name: "replace applied", action: "replace", record: "www.example.com. 300 IN A 192.0.2.12", apply: true, verified: true, current: 1},
+		{name: "replace keeps old records", action: "replace", record: "www.example.com. 300 IN A 192.0.2.12", apply: true, keepOld: true, current: 3},
+		{name: "RRset delete applied", action: "delete", record: "www.example.com. A", apply: true, verified: true, current: 0},
+		{name: "RRset delete ignored by server", action: "delete", record: "www.example.com. A", current: 2},
+		{name: "name delete applied", action: "delete", record: "www.example.com. ANY", apply: true, verified: true, current: 0},
+		{name: "name delete ignored by server", action: "delete", record: "www.example.com. ANY", current: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &updateServer{apply: tt.apply, keepOld: tt.keepOld}
+			for _, s := range zone {
+				server.records = append(server.records, mustRR(t, s))
+			}
+			port := startTestServer(t, server.ServeDNS)
+
+			update, err := ParseUpdate(tt.action, tt.record, "example.com")
+			if err != nil {
+				t.Fatalf("ParseUpdate: %v", err)
+			}
+			spec := QuerySpec{
+				Domain:    "www.example.com",
+				QueryType: update.Type(),
+				Transport: UDP,
+				IPVersion: IPv4,
+				Update:    update,
+			}
+
+			res := ExecuteQuery(spec, testConfig(port))
+			if res.Update == nil {
+				t.Fatalf("no update result, error: %s", res.Error)
+			}
+			if res.Update.Verified != tt.verified {
+				t.Errorf("verified = %v, want %v (error: %s)", res.Update.Verified, tt.verified, res.Error)
+			}
+			if len(res.Update.Current) != tt.current {
+				t.Errorf("current = %q, want %d records", res.Update.Current, tt.current)
+			}
+			if update.rr.Header().Class != dns.ClassINET {
+				t.Errorf("update record class changed to %s", dns.ClassToString[update.rr.Header().Class])
+			}
+		})
+	}
+}
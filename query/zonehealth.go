@@ -0,0 +1,141 @@
+package query
+
+import (
+	"dns_query_utility/config"
+	"dns_query_utility/result"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// checkZoneHealth queries the SOA of the zone containing spec.Domain from
+// every authoritative server address and flags serial, MNAME and RNAME
+// mismatches and unreachable servers. Results are cached in the session per
+// zone so query-all runs check each zone once per worker.
+func (s *Session) checkZoneHealth(spec QuerySpec, cfg config.Config, resolver string) *result.ZoneHealth {
+	zs := s.zoneServers(dns.Fqdn(spec.Domain), spec.IPVersion, cfg, resolver)
+
+	key := resolver + "|" + zs.zone
+	if zs.err == nil {
+		if health, ok := s.zoneHealth[key]; ok {
+			return health
+		}
+	}
+
+	health := &result.ZoneHealth{
+		Zone:    zs.zone,
+		Servers: []result.SOAServerResult{},
+	}
+	if zs.err != nil {
+		health.Error = zs.err.Error()
+		return health
+	}
+
+	query := new(dns.Msg)
+	query.SetQuestion(zs.zone, dns.TypeSOA)
+	query.RecursionDesired = false
+
+	for _, server := range zs.servers {
+		health.Servers = append(health.Servers, querySOA(query, server, spec, cfg))
+	}
+
+	health.Serials, health.Issues = compareSOA(health.Servers)
+	health.Healthy = len(health.Issues) == 0
+
+	s.zoneHealth[key] = health
+	return health
+}
+
+// querySOA fetches the zone's SOA record from one authoritative server address
+func querySOA(query *dns.Msg, server traceServer, spec QuerySpec, cfg config.Config) result.SOAServerResult {
+	soaResult := result.SOAServerResult{
+		Server:  server.name,
+		Address: server.address,
+	}
+
+	response, latency, err := exchangeAuthServer(query, server.address, spec.Transport, cfg)
+	soaResult.LatencyMs = latency
+	if err != nil {
+		soaResult.Error = err.Error()
+		return soaResult
+	}
+	soaResult.Authoritative = response.Authoritative
+
+	if response.Rcode != dns.RcodeSuccess {
+		soaResult.Error = fmt.Sprintf("server returned %s", dns.RcodeToString[response.Rcode])
+		return soaResult
+	}
+
+	for _, rr := range response.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			soaResult.Serial = soa.Serial
+			soaResult.MName = dns.CanonicalName(soa.Ns)
+			soaResult.RName = dns.CanonicalName(soa.Mbox)
+			return soaResult
+		}
+	}
+
+	soaResult.Error = "no SOA record in answer"
+	return soaResult
+}
+
+// compareSOA returns the distinct serials seen and a description of every
+// inconsistency between the servers' SOA records
+func compareSOA(servers []result.SOAServerResult) ([]uint32, []string) {
+	var issues []string
+	serials := make(map[uint32][]string)
+	mnames := make(map[string][]string)
+	rnames := make(map[string][]string)
+
+	for _, server := range servers {
+		if server.Error != "" {
+			issues = append(issues, fmt.Sprintf("unreachable: %s (%s): %s", server.Server, server.Address, server.Error))
+			continue
+		}
+		if !server.Authoritative {
+			issues = append(issues, fmt.Sprintf("lame: %s (%s) did not answer authoritatively", server.Server, server.Address))
+		}
+		serials[server.Serial] = append(serials[server.Serial], server.Address)
+		mnames[server.MName] = append(mnames[server.MName], server.Address)
+		rnames[server.RName] = append(rnames[server.RName], server.Address)
+	}
+
+	var distinct []uint32
+	for serial := range serials {
+		distinct = append(distinct, serial)
+	}
+	sort.Slice(distinct, func(i, j int) bool { return distinct[i] < distinct[j] })
+
+	if len(distinct) > 1 {
+		var groups []string
+		for _, serial := range distinct {
+			groups = append(groups, fmt.Sprintf("%d on %s", serial, strings.Join(serials[serial], ", ")))
+		}
+		issues = append(issues, "serial mismatch: "+strings.Join(groups, "; "))
+	}
+	if len(mnames) > 1 {
+		issues = append(issues, "MNAME differs: "+describeGroups(mnames))
+	}
+	if len(rnames) > 1 {
+		issues = append(issues, "RNAME differs: "+describeGroups(rnames))
+	}
+
+	return distinct, issues
+}
+
+// describeGroups renders value -> addresses groups in a stable order
+func describeGroups(groups map[string][]string) string {
+	var values []string
+	for value := range groups {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var parts []string
+	for _, value := range values {
+		parts = append(parts, fmt.Sprintf("%s on %s", value, strings.Join(groups[value], ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -1,5 +1,7 @@
 package result
 
+import "sort"
+
 // ConsolidateResults groups query results by domain
 func ConsolidateResults(results []QueryResult) []ConsolidatedResult {
 	// Group by domain
@@ -26,19 +28,54 @@ func ConsolidateResults(results []QueryResult) []ConsolidatedResult {
 		// Build type results
 		for _, res := range domainResults {
 			typeRes := TypeResult{
-				Status:          res.Status,
-				LatencyMs:       res.LatencyMs,
-				ResponseCode:    res.ResponseCode,
-				ResolvedIPs:     res.ResolvedIPs,
-				Records:         res.Records,
-				AuthoritativeNS: res.AuthoritativeNS, // NEW: Include in consolidated output
-				Error:           res.Error,
-				Transport:       res.Transport,
-				IPVersion:       res.IPVersion,
-				Timestamp:       res.Timestamp,
+				Status:            res.Status,
+				LatencyMs:         res.LatencyMs,
+				ResponseCode:      res.ResponseCode,
+				ResolvedIPs:       res.ResolvedIPs,
+				Records:           res.Records,
+				Answers:           res.Answers,
+				Authority:         res.Authority,
+				Additional:        res.Additional,
+				AuthoritativeNS:   res.AuthoritativeNS, // NEW: Include in consolidated output
+				ALPN:              res.ALPN,
+				Used0RTT:          res.Used0RTT,
+				Truncated:         res.Truncated,
+				FallbackTransport: res.FallbackTransport,
+				EDNS:              res.EDNS,
+				Header:            res.Header,
+				ResponseSize:      res.ResponseSize,
+				MinTTL:            res.MinTTL,
+				TTLs:              res.TTLs,
+				ECS:               res.ECS,
+				NSID:              res.NSID,
+				EDE:               res.EDE,
+				DNSSEC:            res.DNSSEC,
+				Trace:             res.Trace,
+				Authoritative:     res.Authoritative,
+				Transfer:          res.Transfer,
+				TSIG:              res.TSIG,
+				CNAMEChain:        res.CNAMEChain,
+				Negative:          res.Negative,
+				Update:            res.Update,
+				Error:             res.Error,
+				Transport:         res.Transport,
+				IPVersion:         res.IPVersion,
+				Timestamp:         res.Timestamp,
 			}
 
-			cr.QueryTypes[res.QueryType] = typeRes
+			// Keep CH/HS answers apart from IN answers of the same type
+			key := res.QueryType
+			if res.QueryClass != "" {
+				key = res.QueryClass + " " + key
+			}
+			cr.QueryTypes[key] = typeRes
+
+			if cr.ZoneHealth == nil {
+				cr.ZoneHealth = res.ZoneHealth
+			}
+			if cr.Delegation == nil {
+				cr.Delegation = res.Delegation
+			}
 
 			// Update counters
 			totalLatency += res.LatencyMs
@@ -72,3 +109,26 @@ func ConsolidateResults(results []QueryResult) []ConsolidatedResult {
 
 	return consolidated
 }
+
+// CollectZoneHealth returns one zone health check per zone, sorted by zone
+// name, for the top-level zone_health section. Rows that checked the same
+// zone keep the first unhealthy check, so a mismatch seen by any row is kept.
+func CollectZoneHealth(results []QueryResult) []ZoneHealth {
+	byZone := make(map[string]*ZoneHealth)
+	for _, res := range results {
+		health := res.ZoneHealth
+		if health == nil {
+			continue
+		}
+		if seen, ok := byZone[health.Zone]; !ok || (seen.Healthy && !health.Healthy) {
+			byZone[health.Zone] = health
+		}
+	}
+
+	checks := make([]ZoneHealth, 0, len(byZone))
+	for _, health := range byZone {
+		checks = append(checks, *health)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Zone < checks[j].Zone })
+	return checks
+}
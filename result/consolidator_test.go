@@ -0,0 +1,50 @@
+package result
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectZoneHealth(t *testing.T) {
+	healthy := &ZoneHealth{Zone: "example.com.", Healthy: true, Serials: []uint32{2}}
+	mismatch := &ZoneHealth{Zone: "example.com.", Serials: []uint32{1, 2}, Issues: []string{"serial mismatch"}}
+	other := &ZoneHealth{Zone: "example.org.", Healthy: true, Serials: []uint32{7}}
+
+	tests := []struct {
+		name    string
+		results []QueryResult
+		want    []ZoneHealth
+	}{
+		{
+			name:    "no zone health mode",
+			results: []QueryResult{{Domain: "example.com"}},
+			want:    []ZoneHealth{},
+		},
+		{
+			name: "one check per zone, sorted",
+			results: []QueryResult{
+				{Domain: "example.org", ZoneHealth: other},
+				{Domain: "www.example.com", ZoneHealth: healthy},
+				{Domain: "example.com", ZoneHealth: healthy},
+			},
+			want: []ZoneHealth{*healthy, *other},
+		},
+		{
+			name: "unhealthy check wins",
+			results: []QueryResult{
+				{Domain: "example.com", QueryType: "A", ZoneHealth: healthy},
+				{Domain: "example.com", QueryType: "MX", ZoneHealth: mismatch},
+				{Domain: "example.com", QueryType: "TXT", ZoneHealth: healthy},
+			},
+			want: []ZoneHealth{*mismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CollectZoneHealth(tt.results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollectZoneHealth = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
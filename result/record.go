@@ -0,0 +1,123 @@
+package result
+
+// Record is a typed resource record. Name, Type, Class, TTL and Data are set
+// for every record; the type-specific fields are set for the types that have them.
+type Record struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+	TTL   uint32 `json:"ttl"`
+	Data  string `json:"data"` // Record data in zone file format
+
+	Address string      `json:"address,omitempty"` // A, AAAA
+	Target  string      `json:"target,omitempty"`  // CNAME, DNAME, NS, PTR
+	Text    []string    `json:"text,omitempty"`    // TXT character strings
+	MX      *MXData     `json:"mx,omitempty"`
+	SRV     *SRVData    `json:"srv,omitempty"`
+	SOA     *SOAData    `json:"soa,omitempty"`
+	CAA     *CAAData    `json:"caa,omitempty"`
+	DNSKEY  *DNSKEYData `json:"dnskey,omitempty"`
+	DS      *DSData     `json:"ds,omitempty"`
+	SVCB    *SVCBData   `json:"svcb,omitempty"` // SVCB and HTTPS
+	TLSA    *TLSAData   `json:"tlsa,omitempty"`
+	NAPTR   *NAPTRData  `json:"naptr,omitempty"`
+	SSHFP   *SSHFPData  `json:"sshfp,omitempty"`
+	OPT     *OPTData    `json:"opt,omitempty"` // EDNS pseudo-record in the additional section
+}
+
+// MXData holds the fields of an MX record
+type MXData struct {
+	Preference uint16 `json:"preference"`
+	Exchange   string `json:"exchange"`
+}
+
+// SRVData holds the fields of an SRV record (RFC 2782)
+type SRVData struct {
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+	Port     uint16 `json:"port"`
+	Target   string `json:"target"`
+}
+
+// SOAData holds the fields and timers of an SOA record
+type SOAData struct {
+	MName   string `json:"mname"` // Primary nameserver
+	RName   string `json:"rname"` // Responsible mailbox
+	Serial  uint32 `json:"serial"`
+	Refresh uint32 `json:"refresh"`
+	Retry   uint32 `json:"retry"`
+	Expire  uint32 `json:"expire"`
+	Minimum uint32 `json:"minimum"` // Negative caching TTL (RFC 2308)
+}
+
+// CAAData holds the fields of a CAA record (RFC 8659)
+type CAAData struct {
+	Flag  uint8  `json:"flag"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// DNSKEYData holds the fields of a DNSKEY record
+type DNSKEYData struct {
+	Flags     uint16 `json:"flags"`
+	Protocol  uint8  `json:"protocol"`
+	Algorithm uint8  `json:"algorithm"`
+	KeyTag    uint16 `json:"key_tag"`
+	PublicKey string `json:"public_key"`
+}
+
+// DSData holds the fields of a DS record
+type DSData struct {
+	KeyTag     uint16 `json:"key_tag"`
+	Algorithm  uint8  `json:"algorithm"`
+	DigestType uint8  `json:"digest_type"`
+	Digest     string `json:"digest"`
+}
+
+// SVCBData holds the fields of an SVCB or HTTPS record (RFC 9460)
+type SVCBData struct {
+	Priority uint16            `json:"priority"`
+	Target   string            `json:"target"`
+	Params   map[string]string `json:"params,omitempty"` // e.g. "alpn": "h3,h2"
+}
+
+// TLSAData holds the fields of a TLSA record (RFC 6698)
+type TLSAData struct {
+	Usage        uint8  `json:"usage"`
+	Selector     uint8  `json:"selector"`
+	MatchingType uint8  `json:"matching_type"`
+	Certificate  string `json:"certificate"`
+}
+
+// NAPTRData holds the fields of a NAPTR record (RFC 3403)
+type NAPTRData struct {
+	Order       uint16 `json:"order"`
+	Preference  uint16 `json:"preference"`
+	Flags       string `json:"flags"`
+	Service     string `json:"service"`
+	Regexp      string `json:"regexp"`
+	Replacement string `json:"replacement"`
+}
+
+// SSHFPData holds the fields of an SSHFP record (RFC 4255)
+type SSHFPData struct {
+	Algorithm   uint8  `json:"algorithm"`
+	Type        uint8  `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// OPTData holds the fields of the EDNS OPT pseudo-record (RFC 6891), which
+// reuses the class and TTL of the record header for its own fields
+type OPTData struct {
+	UDPSize       uint16       `json:"udp_size"`
+	Version       uint8        `json:"version"`
+	DO            bool         `json:"do"`
+	ExtendedRcode int          `json:"extended_rcode"`
+	Options       []EDNSOption `json:"options,omitempty"`
+}
+
+// EDNSOption is one option of an OPT record
+type EDNSOption struct {
+	Code uint16 `json:"code"`
+	Data string `json:"data"`
+}
@@ -1,6 +1,9 @@
 package result
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // QueryStatus represents the outcome of a DNS query
 type QueryStatus string
@@ -15,34 +18,344 @@ const (
 	StatusError    QueryStatus = "error"
 )
 
+// DNSSECStatus is the outcome of chain-of-trust validation (RFC 4035 section 4.3)
+type DNSSECStatus string
+
+const (
+	DNSSECSecure        DNSSECStatus = "secure"
+	DNSSECInsecure      DNSSECStatus = "insecure"
+	DNSSECBogus         DNSSECStatus = "bogus"
+	DNSSECIndeterminate DNSSECStatus = "indeterminate"
+)
+
 // QueryResult holds the outcome of a single DNS query
 type QueryResult struct {
-	Domain          string      `json:"domain"`
-	QueryType       string      `json:"query_type"`
-	Transport       string      `json:"transport"`
-	IPVersion       string      `json:"network"`
-	Status          QueryStatus `json:"status"`
-	LatencyMs       float64     `json:"latency_ms"`
-	ResponseCode    int         `json:"response_code"`
-	ResolvedIPs     []string    `json:"resolved_ips,omitempty"`
-	Records         []string    `json:"records,omitempty"`
-	AuthoritativeNS []string    `json:"authoritative_ns"` // NEW: NS records from Authority section
-	Error           string      `json:"error,omitempty"`
-	Timestamp       time.Time   `json:"timestamp"`
+	Domain            string               `json:"domain"`
+	QueryType         string               `json:"query_type"`
+	QueryClass        string               `json:"query_class,omitempty"` // Set for non-IN classes (CH, HS)
+	Transport         string               `json:"transport"`
+	IPVersion         string               `json:"network"`
+	Status            QueryStatus          `json:"status"`
+	LatencyMs         float64              `json:"latency_ms"`
+	ResponseCode      int                  `json:"response_code"`
+	ResolvedIPs       []string             `json:"resolved_ips,omitempty"`
+	Records           []string             `json:"records,omitempty"`            // Legacy "TYPE:value" strings; see Answers
+	Answers           []Record             `json:"answers,omitempty"`            // Typed answer records
+	Authority         []Record             `json:"authority,omitempty"`          // Authority section as sent (referrals, negative-caching SOA)
+	Additional        []Record             `json:"additional,omitempty"`         // Additional section as sent, including glue and the OPT record
+	AuthoritativeNS   []string             `json:"authoritative_ns"`             // NEW: NS records from Authority section
+	ALPN              string               `json:"alpn,omitempty"`               // Negotiated ALPN for DoQ
+	Used0RTT          bool                 `json:"used_0rtt,omitempty"`          // DoQ query was sent as 0-RTT data
+	Truncated         bool                 `json:"truncated,omitempty"`          // UDP response had the TC bit set
+	FallbackTransport string               `json:"fallback_transport,omitempty"` // Transport used to retry a truncated query
+	EDNS              *EDNSInfo            `json:"edns,omitempty"`               // OPT record advertised by the server
+	Header            *HeaderInfo          `json:"header,omitempty"`             // Response message ID and flags
+	ResponseSize      int                  `json:"response_size,omitempty"`      // Response message size in bytes
+	MinTTL            *uint32              `json:"min_ttl,omitempty"`            // Lowest TTL in the answer section
+	TTLs              []RecordTTL          `json:"ttls,omitempty"`               // TTL of each answer record
+	ECS               *ECSInfo             `json:"ecs,omitempty"`                // Client subnet echoed by the server
+	NSID              string               `json:"nsid,omitempty"`               // Server identifier (RFC 5001), as text when printable
+	EDE               []EDEInfo            `json:"ede,omitempty"`                // Extended DNS Errors (RFC 8914)
+	DNSSEC            *DNSSECResult        `json:"dnssec,omitempty"`             // Set in --dnssec-validate mode
+	Trace             []TraceStep          `json:"trace,omitempty"`              // Delegation steps in --trace mode
+	Authoritative     *AuthoritativeResult `json:"authoritative,omitempty"`      // Per-server answers in --authoritative mode
+	ZoneHealth        *ZoneHealth          `json:"zone_health,omitempty"`        // SOA consistency in --zone-health mode
+	Delegation        *DelegationResult    `json:"delegation,omitempty"`         // Parent/child NS and glue checks in --delegation mode
+	Transfer          *TransferInfo        `json:"transfer,omitempty"`           // Zone transfer summary for AXFR/IXFR queries
+	TSIG              *TSIGResult          `json:"tsig,omitempty"`               // Signature verification for TSIG-signed queries
+	CNAMEChain        *CNAMEChain          `json:"cname_chain,omitempty"`        // Ordered CNAME hops when the answer is an alias
+	Negative          *NegativeResult      `json:"negative,omitempty"`           // NXDOMAIN/NODATA analysis and negative-caching TTL
+	Update            *UpdateResult        `json:"update,omitempty"`             // Dynamic update outcome in --update mode
+	Error             string               `json:"error,omitempty"`
+	Timestamp         time.Time            `json:"timestamp"`
+}
+
+// HeaderInfo holds the ID and flags from a response header
+type HeaderInfo struct {
+	ID uint16 `json:"id"`
+	AA bool   `json:"aa"` // Authoritative answer
+	TC bool   `json:"tc"` // Truncated
+	RD bool   `json:"rd"` // Recursion desired
+	RA bool   `json:"ra"` // Recursion available
+	AD bool   `json:"ad"` // Authentic data (DNSSEC validated by the resolver)
+	CD bool   `json:"cd"` // Checking disabled
+}
+
+// Flags returns the set flags in dig style, e.g. "rd ra ad"
+func (h HeaderInfo) Flags() string {
+	var flags []string
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{{h.AA, "aa"}, {h.TC, "tc"}, {h.RD, "rd"}, {h.RA, "ra"}, {h.AD, "ad"}, {h.CD, "cd"}} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// RecordTTL is the TTL of one answer record
+type RecordTTL struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+}
+
+// EDNSInfo describes the OPT pseudo-record returned by the server
+type EDNSInfo struct {
+	UDPSize uint16 `json:"udp_size"`
+	Version uint8  `json:"version"`
+	DO      bool   `json:"do"`
+}
+
+// ECSInfo describes the EDNS Client Subnet option (RFC 7871) in a response
+type ECSInfo struct {
+	Requested    string `json:"requested"`     // Subnet sent in the query
+	Subnet       string `json:"subnet"`        // Subnet echoed by the server
+	SourcePrefix uint8  `json:"source_prefix"` // Echoed source prefix length
+	ScopePrefix  uint8  `json:"scope_prefix"`  // Prefix length the answer is valid for
+}
+
+// EDEInfo is an Extended DNS Error option (RFC 8914) from a response
+type EDEInfo struct {
+	Code    uint16 `json:"code"`
+	Purpose string `json:"purpose,omitempty"` // Registered meaning of the code, e.g. "DNSSEC Bogus"
+	Text    string `json:"text,omitempty"`    // Extra text from the server
+}
+
+// DNSSECResult reports the validation status of an answer and why it was reached
+type DNSSECResult struct {
+	Status DNSSECStatus `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Zone   string       `json:"zone,omitempty"` // Signer zone (or the zone where validation stopped)
+}
+
+// TraceStep is one server queried while resolving iteratively from the root
+type TraceStep struct {
+	Zone          string   `json:"zone"`    // Zone the server was queried as authoritative for
+	Server        string   `json:"server"`  // Nameserver name
+	Address       string   `json:"address"` // Address the query was sent to
+	LatencyMs     float64  `json:"latency_ms"`
+	Rcode         string   `json:"rcode,omitempty"`
+	Authoritative bool     `json:"authoritative,omitempty"`
+	Referral      string   `json:"referral,omitempty"`    // Child zone the server delegated to
+	Nameservers   []string `json:"nameservers,omitempty"` // NS names given in the referral
+	Error         string   `json:"error,omitempty"`
+}
+
+// AuthoritativeResult compares the answers of every authoritative server of a zone
+type AuthoritativeResult struct {
+	Zone       string             `json:"zone"`
+	Consistent bool               `json:"consistent"`       // All responding servers returned the same answer
+	Reason     string             `json:"reason,omitempty"` // Why the servers are not consistent
+	Servers    []AuthServerResult `json:"servers"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// AuthServerResult is the answer of one authoritative server address
+type AuthServerResult struct {
+	Server        string   `json:"server"`  // Nameserver name
+	Address       string   `json:"address"` // Address the query was sent to
+	LatencyMs     float64  `json:"latency_ms"`
+	Rcode         string   `json:"rcode,omitempty"`
+	Authoritative bool     `json:"authoritative"` // AA flag
+	Answers       []string `json:"answers,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// ZoneHealth reports whether every authoritative server of a zone serves the same SOA
+type ZoneHealth struct {
+	Zone    string            `json:"zone"`
+	Healthy bool              `json:"healthy"`
+	Serials []uint32          `json:"serials,omitempty"` // Distinct serials seen across servers
+	Issues  []string          `json:"issues,omitempty"`  // Serial/MNAME/RNAME mismatches and unreachable servers
+	Servers []SOAServerResult `json:"servers"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// SOAServerResult is the SOA record served by one authoritative server address
+type SOAServerResult struct {
+	Server        string  `json:"server"`  // Nameserver name
+	Address       string  `json:"address"` // Address the query was sent to
+	LatencyMs     float64 `json:"latency_ms"`
+	Authoritative bool    `json:"authoritative"` // AA flag
+	Serial        uint32  `json:"serial,omitempty"`
+	MName         string  `json:"mname,omitempty"`
+	RName         string  `json:"rname,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// DelegationResult compares a zone's delegation in its parent with the zone itself
+type DelegationResult struct {
+	Zone            string             `json:"zone"`
+	Parent          string             `json:"parent"`
+	Consistent      bool               `json:"consistent"`
+	ParentNS        []string           `json:"parent_ns"`                   // NS set in the parent's referral
+	ChildNS         []string           `json:"child_ns"`                    // NS set served by the zone's own servers
+	MissingAtChild  []string           `json:"missing_at_child,omitempty"`  // Delegated by the parent but not listed by the child
+	MissingAtParent []string           `json:"missing_at_parent,omitempty"` // Listed by the child but not delegated by the parent
+	Glue            []GlueCheck        `json:"glue,omitempty"`
+	Servers         []DelegationServer `json:"servers"`
+	Issues          []string           `json:"issues,omitempty"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// GlueCheck compares the glue for an in-bailiwick nameserver with its
+// authoritative A/AAAA records
+type GlueCheck struct {
+	Nameserver string   `json:"nameserver"`
+	Glue       []string `json:"glue"`  // Addresses in the parent's referral
+	Child      []string `json:"child"` // Addresses served by the zone
+	Match      bool     `json:"match"`
+}
+
+// DelegationServer is one delegated nameserver address and whether it is lame
+type DelegationServer struct {
+	Server    string  `json:"server"`
+	Address   string  `json:"address"`
+	LatencyMs float64 `json:"latency_ms"`
+	Lame      bool    `json:"lame"`
+	Reason    string  `json:"reason,omitempty"` // Why the server is lame
+}
+
+// TransferInfo summarizes a zone transfer (AXFR/IXFR). The transferred
+// records are in QueryResult.Answers, and in QueryResult.Records in zone file format.
+type TransferInfo struct {
+	Serial      uint32 `json:"serial"` // SOA serial of the transferred zone
+	RecordCount int    `json:"record_count"`
+	Envelopes   int    `json:"envelopes"`           // Messages received
+	TSIGKey     string `json:"tsig_key,omitempty"`  // Key used to sign the transfer
+	ZoneFile    string `json:"zone_file,omitempty"` // Path the zone was written to
+}
+
+// CNAMEChain is the chain of aliases from the queried name to the name that
+// holds the records, in the order they are followed
+type CNAMEChain struct {
+	Hops      []CNAMEHop `json:"hops"`
+	Final     string     `json:"final"`                // Name the chain ends at
+	Complete  bool       `json:"complete"`             // Records of the queried type were found for Final
+	Loop      bool       `json:"loop,omitempty"`       // A target points back to an earlier name in the chain
+	TooLong   bool       `json:"too_long,omitempty"`   // More hops than --max-cname-depth
+	FollowUps int        `json:"follow_ups,omitempty"` // Queries sent with --follow-cname to continue the chain
+	Answers   []Record   `json:"answers,omitempty"`    // Records for Final found by the follow-up queries
+	Error     string     `json:"error,omitempty"`      // Why a followed chain could not be completed
+}
+
+// CNAMEHop is one alias in a CNAME chain
+type CNAMEHop struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	TTL    uint32 `json:"ttl"`
+	Server string `json:"server"` // Server whose response contained the hop
+}
+
+// TakeoverRisk rates how likely an alias can be taken over by whoever
+// claims its target
+type TakeoverRisk string
+
+const (
+	TakeoverHigh          TakeoverRisk = "high"          // Target is NXDOMAIN and belongs to a known provider
+	TakeoverMedium        TakeoverRisk = "medium"        // Target is NXDOMAIN; it may be registrable or claimable
+	TakeoverLow           TakeoverRisk = "low"           // Target is at a provider that must be checked for an unclaimed resource
+	TakeoverIndeterminate TakeoverRisk = "indeterminate" // Target failed with SERVFAIL, so whether it exists is unknown
+)
+
+// TakeoverReport is the outcome of --takeover-audit over the aliased names
+// of the inventory
+type TakeoverReport struct {
+	Checked    int               `json:"checked"`    // Aliased names audited
+	Signatures int               `json:"signatures"` // Provider signatures matched against
+	Findings   []TakeoverFinding `json:"findings"`
+}
+
+// TakeoverFinding is an alias whose target may be claimable by a third party
+type TakeoverFinding struct {
+	Domain   string       `json:"domain"`
+	Chain    []string     `json:"chain"`  // Names from Domain to the final target
+	Target   string       `json:"target"` // Target the finding is about
+	Provider string       `json:"provider,omitempty"`
+	Risk     TakeoverRisk `json:"risk"`
+	Reason   string       `json:"reason"`
+}
+
+// NegativeKind distinguishes the ways a response can carry no answer
+type NegativeKind string
+
+const (
+	NegativeNXDomain         NegativeKind = "nxdomain"           // The name does not exist
+	NegativeNoData           NegativeKind = "nodata"             // The name exists but has no records of the queried type
+	NegativeEmptyNonTerminal NegativeKind = "empty_non_terminal" // The name has no records at all, only names below it
+	NegativeReferral         NegativeKind = "referral"           // The server referred to another zone instead of answering
+)
+
+// NegativeResult analyzes an NXDOMAIN or NODATA response. Resolvers cache it
+// for the lower of the SOA TTL and the SOA MINIMUM field (RFC 2308 section 5).
+type NegativeResult struct {
+	Kind        NegativeKind `json:"kind"`
+	Zone        string       `json:"zone,omitempty"`         // Owner of the SOA in the authority section
+	SOA         *Record      `json:"soa,omitempty"`          // Unset when the server sent no SOA
+	NegativeTTL *uint32      `json:"negative_ttl,omitempty"` // How long the negative answer may be cached
+	Evidence    string       `json:"evidence,omitempty"`     // How NODATA was told apart from an empty non-terminal
+	// Inconclusive is set when the ANY probe was refused or failed, so the
+	// name may still be an empty non-terminal
+	Inconclusive bool `json:"inconclusive,omitempty"`
+}
+
+// TSIGResult records the key a query was signed with and whether the
+// response signature verified
+type TSIGResult struct {
+	Key       string `json:"key"`
+	Algorithm string `json:"algorithm"`
+	Signed    bool   `json:"response_signed"` // Response carried a TSIG record
+	Verified  bool   `json:"verified"`        // Response MAC and time are valid
+	Error     string `json:"error,omitempty"` // Server TSIG error (BADSIG, BADKEY, BADTIME) or local verification failure
+}
+
+// UpdateResult records a dynamic update (RFC 2136) and its verification
+type UpdateResult struct {
+	Action   string   `json:"action"` // add, delete or replace
+	Zone     string   `json:"zone"`
+	Record   string   `json:"record"`
+	Verified bool     `json:"verified"`          // Follow-up query shows the change
+	Current  []string `json:"current,omitempty"` // Records served for the name and type after the update
 }
 
 // TypeResult holds the result for a specific query type
 type TypeResult struct {
-	Status          QueryStatus `json:"status"`
-	LatencyMs       float64     `json:"latency_ms"`
-	ResponseCode    int         `json:"response_code"`
-	ResolvedIPs     []string    `json:"ips,omitempty"`
-	Records         []string    `json:"records,omitempty"`
-	AuthoritativeNS []string    `json:"authoritative_ns,omitempty"` // NEW: NS records from Authority section
-	Error           string      `json:"error,omitempty"`
-	Transport       string      `json:"transport"`
-	IPVersion       string      `json:"network"`
-	Timestamp       time.Time   `json:"timestamp"`
+	Status            QueryStatus          `json:"status"`
+	LatencyMs         float64              `json:"latency_ms"`
+	ResponseCode      int                  `json:"response_code"`
+	ResolvedIPs       []string             `json:"ips,omitempty"`
+	Records           []string             `json:"records,omitempty"`
+	Answers           []Record             `json:"answers,omitempty"`
+	Authority         []Record             `json:"authority,omitempty"`
+	Additional        []Record             `json:"additional,omitempty"`
+	AuthoritativeNS   []string             `json:"authoritative_ns,omitempty"` // NEW: NS records from Authority section
+	ALPN              string               `json:"alpn,omitempty"`
+	Used0RTT          bool                 `json:"used_0rtt,omitempty"`
+	Truncated         bool                 `json:"truncated,omitempty"`
+	FallbackTransport string               `json:"fallback_transport,omitempty"`
+	EDNS              *EDNSInfo            `json:"edns,omitempty"`
+	Header            *HeaderInfo          `json:"header,omitempty"`
+	ResponseSize      int                  `json:"response_size,omitempty"`
+	MinTTL            *uint32              `json:"min_ttl,omitempty"`
+	TTLs              []RecordTTL          `json:"ttls,omitempty"`
+	ECS               *ECSInfo             `json:"ecs,omitempty"`
+	NSID              string               `json:"nsid,omitempty"`
+	EDE               []EDEInfo            `json:"ede,omitempty"`
+	DNSSEC            *DNSSECResult        `json:"dnssec,omitempty"`
+	Trace             []TraceStep          `json:"trace,omitempty"`
+	Authoritative     *AuthoritativeResult `json:"authoritative,omitempty"`
+	Transfer          *TransferInfo        `json:"transfer,omitempty"`
+	TSIG              *TSIGResult          `json:"tsig,omitempty"`
+	CNAMEChain        *CNAMEChain          `json:"cname_chain,omitempty"`
+	Negative          *NegativeResult      `json:"negative,omitempty"`
+	Update            *UpdateResult        `json:"update,omitempty"`
+	Error             string               `json:"error,omitempty"`
+	Transport         string               `json:"transport"`
+	IPVersion         string               `json:"network"`
+	Timestamp         time.Time            `json:"timestamp"`
 }
 
 // ConsolidatedResult holds all query types for a single domain
@@ -50,6 +363,8 @@ type ConsolidatedResult struct {
 	Domain     string                `json:"domain"`
 	QueryTypes map[string]TypeResult `json:"query_types"`
 	Summary    ConsolidatedSummary   `json:"summary"`
+	ZoneHealth *ZoneHealth           `json:"zone_health,omitempty"` // Same for every query type, reported once
+	Delegation *DelegationResult     `json:"delegation,omitempty"`  // Same for every query type, reported once
 }
 
 // ConsolidatedSummary provides aggregate statistics for a domain
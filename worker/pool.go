@@ -41,12 +41,16 @@ func (p *Pool) worker(id int) {
         fmt.Printf("[Worker %d] Started\n", id)
     }
 
+    // Each worker keeps its own session so DoQ connections are reused across its queries
+    session := query.NewSession()
+    defer session.Close()
+
     for spec := range p.jobs {
         if p.verbose {
             fmt.Printf("[Worker %d] Processing: %s (type=%s)\n", id, spec.Domain, spec.QueryType)
         }
 
-        res := query.ExecuteQuery(spec, p.config)
+        res := session.ExecuteQuery(spec, p.config)
         p.results <- res
 
         if p.verbose {